@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"maps"
 	"os"
+	"runtime"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/midbel/distance"
 	"github.com/midbel/packit/internal/build"
+	"github.com/midbel/packit/internal/deb"
 	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/version"
 )
 
 var commands = map[string]func([]string) error{
@@ -24,6 +29,15 @@ var commands = map[string]func([]string) error{
 	"show-files":        runFiles,
 	"show-dependencies": runDependencies,
 	"convert":           runConvert,
+	"repo":              runRepo,
+	"serve":             runServe,
+	"vercmp":            runVercmp,
+	"install":           runInstall,
+	"remove":            runRemove,
+	"extract":           runExtract,
+	"diff":              runDiff,
+	"init":              runInit,
+	"lint":              runLint,
 }
 
 func main() {
@@ -38,6 +52,16 @@ func main() {
 		fmt.Fprintln(os.Stderr, "  content             list of files in a package")
 		fmt.Fprintln(os.Stderr, "  show-files          list of files that will be included in package")
 		fmt.Fprintln(os.Stderr, "  show-dependencies   list of dependencies required by package")
+		fmt.Fprintln(os.Stderr, "  convert             convert a deb package into a rpm package and back")
+		fmt.Fprintln(os.Stderr, "  repo                generate repository metadata for a directory of packages")
+		fmt.Fprintln(os.Stderr, "  serve               serve a directory of packages as a repository over HTTP")
+		fmt.Fprintln(os.Stderr, "  vercmp              compare two versions of a package")
+		fmt.Fprintln(os.Stderr, "  install             install a package into a root directory")
+		fmt.Fprintln(os.Stderr, "  remove              remove a package installed into a root directory")
+		fmt.Fprintln(os.Stderr, "  extract             extract the files of a package into a directory")
+		fmt.Fprintln(os.Stderr, "  diff                compare two packages")
+		fmt.Fprintln(os.Stderr, "  init                create a Packfile from the context directory or an existing package")
+		fmt.Fprintln(os.Stderr, "  lint                check a Packfile for common mistakes")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "usage: packit <command> [<args>]")
 		os.Exit(2)
@@ -73,18 +97,34 @@ func main() {
 
 func runConvert(args []string) error {
 	var (
-		set = flag.NewFlagSet("convert", flag.ExitOnError)
+		set  = flag.NewFlagSet("convert", flag.ExitOnError)
+		kind = set.String("k", "", "package type")
+		dist = set.String("d", "", "directory where package will be written")
 	)
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "convert a package into another package format")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  -k                 type of package to create (rpm or deb)")
+		fmt.Fprintln(os.Stderr, "  -d                 folder where the converted package will be saved")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit convert [OPTIONS] <PACKAGE>")
+		os.Exit(2)
+	}
 	if err := set.Parse(args); err != nil {
 		return err
 	}
-	return nil
+	if set.NArg() == 0 {
+		return fmt.Errorf("missing package")
+	}
+	return build.Convert(set.Arg(0), *kind, *dist)
 }
 
 func runDependencies(args []string) error {
 	var (
-		set = flag.NewFlagSet("show-dependencies", flag.ExitOnError)
-		cfg packfile.DecoderConfig
+		set    = flag.NewFlagSet("show-dependencies", flag.ExitOnError)
+		cfg    packfile.DecoderConfig
+		output = set.String("o", build.OutputText, "output format")
 	)
 	set.StringVar(&cfg.Packfile, "f", "Packfile", "package file")
 	set.Usage = func() {
@@ -94,6 +134,7 @@ func runDependencies(args []string) error {
 		fmt.Fprintln(os.Stderr, "  -f                 Packfile used to build the package")
 		fmt.Fprintln(os.Stderr, "  --no-ignore        keep all files even if present in a .pktignore file")
 		fmt.Fprintln(os.Stderr, "  -i, --ignore-file  file with patterns to be excluded from final package")
+		fmt.Fprintln(os.Stderr, "  -o                 output format: text, json, yaml or template=<go template>")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "Usage: packit show-dependencies [OPTIONS] <CONTEXT>")
 		fmt.Fprintln(os.Stderr)
@@ -102,17 +143,18 @@ func runDependencies(args []string) error {
 	if err := set.Parse(args); err != nil {
 		return err
 	}
-	_, err := decodePackage(set.Arg(0), &cfg)
+	pkg, err := decodePackage(set.Arg(0), &cfg)
 	if err != nil {
 		return err
 	}
-	return nil
+	return build.WriteDependencies(pkg.Depends, *output, os.Stdout)
 }
 
 func runFiles(args []string) error {
 	var (
-		set = flag.NewFlagSet("show-files", flag.ExitOnError)
-		cfg packfile.DecoderConfig
+		set    = flag.NewFlagSet("show-files", flag.ExitOnError)
+		cfg    packfile.DecoderConfig
+		output = set.String("o", build.OutputText, "output format")
 	)
 	set.StringVar(&cfg.Packfile, "f", "Packfile", "package file")
 	set.StringVar(&cfg.IgnoreFile, "i", ".pktignore", "file with patterns to use")
@@ -124,6 +166,7 @@ func runFiles(args []string) error {
 		fmt.Fprintln(os.Stderr, "  -f                 Packfile used to build the package")
 		fmt.Fprintln(os.Stderr, "  --no-ignore        keep all files even if present in a .pktignore file")
 		fmt.Fprintln(os.Stderr, "  -i, --ignore-file  file with patterns to be excluded from final package")
+		fmt.Fprintln(os.Stderr, "  -o                 output format: text, json, yaml or template=<go template>")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "Usage: packit show-files [OPTIONS] <CONTEXT>")
 		fmt.Fprintln(os.Stderr)
@@ -136,22 +179,25 @@ func runFiles(args []string) error {
 	if err != nil {
 		return err
 	}
-	for _, r := range pkg.Files {
-		fmt.Fprintln(os.Stdout, r.Path)
-	}
-	return nil
+	return build.WriteResources(pkg.Files, *output, os.Stdout)
 }
 
 func runBuild(args []string) error {
 	var (
-		set   = flag.NewFlagSet("build", flag.ExitOnError)
-		build build.PackageBuilder
+		set     = flag.NewFlagSet("build", flag.ExitOnError)
+		build   build.PackageBuilder
+		kinds   = set.String("k", "", "package types")
+		archs   = set.String("arch", "", "package architectures")
+		workers = set.Int("j", runtime.NumCPU(), "number of packages built concurrently")
 	)
-	set.StringVar(&build.Type, "k", "", "package type")
 	set.StringVar(&build.File, "f", "Packfile", "package file")
 	set.StringVar(&build.Dist, "d", "", "directory where package will be written")
 	set.BoolVar(&build.OnlyDocs, "only-docs", false, "build documentation package only")
 	set.BoolVar(&build.SplitDocs, "split-docs", false, "build binary and documentation package separately")
+	set.BoolVar(&build.DebugPackage, "debug-package", false, "build debug symbols package separately")
+	set.StringVar(&build.SignKey, "sign-key", "", "private key used to sign package")
+	set.StringVar(&build.SignRole, "sign-role", "", "role of the signature of deb package")
+	set.BoolVar(&build.Reproducible, "reproducible", false, "build a reproducible package")
 
 	set.Usage = func() {
 		fmt.Fprintln(os.Stderr, "build a new package")
@@ -160,12 +206,18 @@ func runBuild(args []string) error {
 		fmt.Fprintln(os.Stderr, "  packit make")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "Options:")
-		fmt.Fprintln(os.Stderr, "  -k                 type of package to build (rpm or deb)")
+		fmt.Fprintln(os.Stderr, "  -k                 comma separated types of package to build (rpm, deb, ipk, apk or arch)")
+		fmt.Fprintln(os.Stderr, "  --arch             comma separated architectures of package to build")
+		fmt.Fprintln(os.Stderr, "  -j                 number of packages built concurrently")
 		fmt.Fprintln(os.Stderr, "  -f                 the Packfile used to build the package")
 		fmt.Fprintln(os.Stderr, "  -d                 folder where the final package will be saved")
 		fmt.Fprintln(os.Stderr, "  -i, --ignore-file  file with patterns to be excluded from final package")
 		fmt.Fprintln(os.Stderr, "  --split-docs       split packages in binary and documentation package")
 		fmt.Fprintln(os.Stderr, "  --only-docs        build documentation package only")
+		fmt.Fprintln(os.Stderr, "  --debug-package    move debug symbols of ELF binaries in a separate package")
+		fmt.Fprintln(os.Stderr, "  --sign-key         private key used to sign the package (OpenPGP or PEM for apk)")
+		fmt.Fprintln(os.Stderr, "  --sign-role        role of the signature of deb package (origin or builder)")
+		fmt.Fprintln(os.Stderr, "  --reproducible     build a reproducible package (SOURCE_DATE_EPOCH or 0)")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "Usage: packit build [OPTIONS] <CONTEXT>")
 		os.Exit(2)
@@ -177,7 +229,332 @@ func runBuild(args []string) error {
 		return fmt.Errorf("missing context")
 	}
 
-	return build.BuildPackage(set.Arg(0))
+	var (
+		targetKinds = splitList(*kinds)
+		targetArchs = splitList(*archs)
+	)
+	if len(targetKinds) <= 1 && len(targetArchs) <= 1 {
+		build.Type = strings.Join(targetKinds, "")
+		build.Arch = strings.Join(targetArchs, "")
+		return build.BuildPackage(set.Arg(0))
+	}
+	targets, err := build.BuildTargets(set.Arg(0), targetKinds, targetArchs, *workers)
+	if err != nil && len(targets) == 0 {
+		return err
+	}
+	var failed int
+	for _, t := range targets {
+		if t.Err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s: %s", t, t.Err)
+			fmt.Fprintln(os.Stderr)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%s: ok", t)
+		fmt.Fprintln(os.Stdout)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d package(s) failed to build", failed)
+	}
+	return err
+}
+
+func splitList(str string) []string {
+	var list []string
+	for _, s := range strings.Split(str, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func runRepo(args []string) error {
+	var (
+		set  = flag.NewFlagSet("repo", flag.ExitOnError)
+		repo build.RepoBuilder
+	)
+	set.StringVar(&repo.Suite, "suite", deb.DefaultSuite, "suite of the repository")
+	set.StringVar(&repo.Component, "component", deb.DefaultComponent, "component of the repository")
+	set.StringVar(&repo.Origin, "origin", "", "origin of the repository")
+	set.StringVar(&repo.Label, "label", "", "label of the repository")
+	set.StringVar(&repo.SignKey, "sign-key", "", "private key used to sign repository metadata")
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "generate repository metadata for the packages found in the given directory")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  --suite            suite (and codename) of the deb repository (default: stable)")
+		fmt.Fprintln(os.Stderr, "  --component        component of the deb repository (default: main)")
+		fmt.Fprintln(os.Stderr, "  --origin           origin of the deb repository")
+		fmt.Fprintln(os.Stderr, "  --label            label of the deb repository")
+		fmt.Fprintln(os.Stderr, "  --sign-key         armored OpenPGP private key used to sign the metadata")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit repo [OPTIONS] <deb|rpm> <DIRECTORY>")
+		os.Exit(2)
+	}
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	return repo.Build(set.Arg(0), set.Arg(1))
+}
+
+func runServe(args []string) error {
+	var (
+		set  = flag.NewFlagSet("serve", flag.ExitOnError)
+		serv build.RepoServer
+	)
+	set.StringVar(&serv.Addr, "addr", ":8080", "address to listen on")
+	set.DurationVar(&serv.Interval, "interval", 2*time.Second, "delay between checks for new packages")
+	set.StringVar(&serv.Suite, "suite", deb.DefaultSuite, "suite of the repository")
+	set.StringVar(&serv.Component, "component", deb.DefaultComponent, "component of the repository")
+	set.StringVar(&serv.SignKey, "sign-key", "", "private key used to sign repository metadata")
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "serve the packages of the given directory and their repository metadata over HTTP")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  --addr             address to listen on (default: :8080)")
+		fmt.Fprintln(os.Stderr, "  --interval         delay between checks for new packages (default: 2s, 0 to disable)")
+		fmt.Fprintln(os.Stderr, "  --suite            suite (and codename) of the deb repository (default: stable)")
+		fmt.Fprintln(os.Stderr, "  --component        component of the deb repository (default: main)")
+		fmt.Fprintln(os.Stderr, "  --sign-key         armored OpenPGP private key used to sign the deb/rpm metadata")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit serve [OPTIONS] <DIRECTORY>")
+		os.Exit(2)
+	}
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	return serv.Serve(set.Arg(0))
+}
+
+func runVercmp(args []string) error {
+	set := flag.NewFlagSet("vercmp", flag.ExitOnError)
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "compare two versions with the rules of the given package type")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "print -1, 0 or 1 if the first version is respectively older, equal or newer than the second")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit vercmp <deb|ipk|rpm|arch> <VERSION> <VERSION>")
+		os.Exit(2)
+	}
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	if set.NArg() != 3 {
+		set.Usage()
+	}
+	cmp, err := version.Compare(set.Arg(0), set.Arg(1), set.Arg(2))
+	if err == nil {
+		fmt.Fprintln(os.Stdout, cmp)
+	}
+	return err
+}
+
+func runInstall(args []string) error {
+	var (
+		set  = flag.NewFlagSet("install", flag.ExitOnError)
+		inst build.Installer
+	)
+	set.StringVar(&inst.Root, "root", "", "root directory where package is installed")
+	set.BoolVar(&inst.NoScripts, "no-scripts", false, "do not run maintainer scripts")
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "install (or upgrade) the given packages into a root directory")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  --root             root directory where packages are installed")
+		fmt.Fprintln(os.Stderr, "  --no-scripts       do not run the maintainer scripts of the packages")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit install [OPTIONS] <PACKAGE...>")
+		os.Exit(2)
+	}
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	for _, file := range set.Args() {
+		if err := inst.Install(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runRemove(args []string) error {
+	var (
+		set  = flag.NewFlagSet("remove", flag.ExitOnError)
+		inst build.Installer
+	)
+	set.StringVar(&inst.Root, "root", "", "root directory where package is installed")
+	set.BoolVar(&inst.NoScripts, "no-scripts", false, "do not run maintainer scripts")
+	set.BoolVar(&inst.Purge, "purge", false, "also remove configuration files of deb packages")
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "remove the given packages from a root directory")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  --root             root directory where packages are installed")
+		fmt.Fprintln(os.Stderr, "  --no-scripts       do not run the maintainer scripts of the packages")
+		fmt.Fprintln(os.Stderr, "  --purge            remove configuration files of deb packages too")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit remove [OPTIONS] <NAME...>")
+		os.Exit(2)
+	}
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	for _, name := range set.Args() {
+		if err := inst.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runExtract(args []string) error {
+	var (
+		set = flag.NewFlagSet("extract", flag.ExitOnError)
+		ext build.Extractor
+	)
+	set.StringVar(&ext.Dir, "d", ".", "directory where files are extracted")
+	set.BoolVar(&ext.Control, "control", false, "also extract control files and scripts")
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "extract the files of a package into a directory")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  -d                 directory where files are extracted")
+		fmt.Fprintln(os.Stderr, "  --control          extract control files (DEBIAN) or scripts and header (RPM) too")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit extract [OPTIONS] <PACKAGE>")
+		os.Exit(2)
+	}
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	if set.NArg() != 1 {
+		set.Usage()
+	}
+	return ext.Extract(set.Arg(0))
+}
+
+func runInit(args []string) error {
+	var (
+		set  = flag.NewFlagSet("init", flag.ExitOnError)
+		from = set.String("from", "", "package used to create the Packfile")
+	)
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "create a Packfile in the context directory")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "without --from, the context directory is inspected to write a starter Packfile")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  --from             deb or rpm package used to create the Packfile")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit init [OPTIONS] [CONTEXT]")
+		os.Exit(2)
+	}
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return build.InitProject(set.Arg(0))
+	}
+	return build.InitFromPackage(*from, set.Arg(0))
+}
+
+func runLint(args []string) error {
+	var (
+		set      = flag.NewFlagSet("lint", flag.ExitOnError)
+		lint     build.Linter
+		kinds    = set.String("k", "", "package types")
+		rules    = set.String("rules", "", "rules to check")
+		skip     = set.String("skip", "", "rules to skip")
+		severity = set.String("severity", "", "severity of rules")
+		list     = set.Bool("list", false, "list available rules")
+		output   = set.String("o", build.OutputText, "output format")
+	)
+	set.StringVar(&lint.Packfile, "f", "Packfile", "package file")
+	set.StringVar(&lint.IgnoreFile, "i", "", "file with patterns to use")
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "check a Packfile for common mistakes")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  -k                 comma separated types of package checked (rpm, deb, ipk, apk or arch)")
+		fmt.Fprintln(os.Stderr, "  -f                 Packfile to check")
+		fmt.Fprintln(os.Stderr, "  -i                 file with patterns to be excluded from final package")
+		fmt.Fprintln(os.Stderr, "  --rules            comma separated list of rules to check")
+		fmt.Fprintln(os.Stderr, "  --skip             comma separated list of rules to skip")
+		fmt.Fprintln(os.Stderr, "  --severity         comma separated list of rule=severity (error, warning or info)")
+		fmt.Fprintln(os.Stderr, "  --list             print the available rules and their severity")
+		fmt.Fprintln(os.Stderr, "  -o                 output format: text, json, yaml or template=<go template>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit lint [OPTIONS] <CONTEXT>")
+		os.Exit(2)
+	}
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	if *list {
+		for _, r := range build.LintRules() {
+			fmt.Printf("%-24s %-8s %s", r.Name, r.Severity, r.Desc)
+			fmt.Println()
+		}
+		return nil
+	}
+	lint.Types = splitList(*kinds)
+	lint.Rules = splitList(*rules)
+	lint.Skip = splitList(*skip)
+	lint.Severities = make(map[string]string)
+	for _, s := range splitList(*severity) {
+		name, level, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("%s: rule=severity expected", s)
+		}
+		lint.Severities[strings.TrimSpace(name)] = strings.TrimSpace(level)
+	}
+	issues, err := lint.Lint(set.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := build.WriteLintIssues(issues, *output, os.Stdout); err != nil {
+		return err
+	}
+	if build.HasLintErrors(issues) {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func runDiff(args []string) error {
+	var (
+		set    = flag.NewFlagSet("diff", flag.ExitOnError)
+		asJSON = set.Bool("json", false, "print differences as JSON")
+	)
+	set.Usage = func() {
+		fmt.Fprintln(os.Stderr, "compare the metadata, dependencies, files and scripts of two packages")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  --json             print the differences as a JSON document")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit diff [OPTIONS] <OLD> <NEW>")
+		os.Exit(2)
+	}
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	if set.NArg() != 2 {
+		set.Usage()
+	}
+	diff, err := build.Diff(set.Arg(0), set.Arg(1))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		err = diff.WriteJSON(os.Stdout)
+	} else {
+		err = diff.WriteReport(os.Stdout)
+	}
+	if err == nil && !diff.Equal() {
+		os.Exit(1)
+	}
+	return err
 }
 
 func runInspect(args []string) error {
@@ -185,6 +562,7 @@ func runInspect(args []string) error {
 		set       = flag.NewFlagSet("inspect", flag.ExitOnError)
 		printAll  = set.Bool("a", false, "print all informations of package")
 		printDeps = set.Bool("d", false, "print only dependencies of package")
+		output    = set.String("o", build.OutputText, "output format")
 	)
 	set.Usage = func() {
 		fmt.Fprintln(os.Stderr, "display information of the given package")
@@ -195,50 +573,60 @@ func runInspect(args []string) error {
 		fmt.Fprintln(os.Stderr, "Options:")
 		fmt.Fprintln(os.Stderr, "  -d  print only the dependencies of the given package")
 		fmt.Fprintln(os.Stderr, "  -a  print information and dependencies of the given package")
+		fmt.Fprintln(os.Stderr, "  -o  output format: text, json, yaml or template=<go template>")
 		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Usage: packit inspect <PACKAGE>")
+		fmt.Fprintln(os.Stderr, "Usage: packit inspect [OPTIONS] <PACKAGE>")
 		os.Exit(2)
 	}
 	if err := set.Parse(args); err != nil {
 		return err
 	}
-	return build.Info(set.Arg(0), *printAll, *printDeps, os.Stdout)
+	return build.Info(set.Arg(0), *printAll, *printDeps, *output, os.Stdout)
 }
 
 func runContent(args []string) error {
-	set := flag.NewFlagSet("content", flag.ExitOnError)
+	var (
+		set    = flag.NewFlagSet("content", flag.ExitOnError)
+		output = set.String("o", build.OutputText, "output format")
+	)
 	set.Usage = func() {
 		fmt.Fprintln(os.Stderr, "display files and directories of the given package")
 		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Usage: packit content <PACKAGE>")
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  -o                 output format: text, json, yaml or template=<go template>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit content [OPTIONS] <PACKAGE>")
 		os.Exit(2)
 	}
 	if err := set.Parse(args); err != nil {
 		return err
 	}
-	return build.Content(set.Arg(0), os.Stdout)
+	return build.Content(set.Arg(0), *output, os.Stdout)
 }
 
 func runVerify(args []string) error {
-	set := flag.NewFlagSet("verify", flag.ExitOnError)
+	var (
+		set     = flag.NewFlagSet("verify", flag.ExitOnError)
+		keyring = set.String("keyring", "", "public keys used to check package signatures")
+		output  = set.String("o", build.OutputText, "output format")
+	)
 	set.Usage = func() {
 		fmt.Fprintln(os.Stderr, "verify integrity of the given package")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "Aliases:")
 		fmt.Fprintln(os.Stderr, "  packit check")
 		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Usage: packit verify <PACKAGE>")
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  --keyring          armored public keys used to check the signatures of the package")
+		fmt.Fprintln(os.Stderr, "  -o                 output format: text, json, yaml or template=<go template>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Usage: packit verify [OPTIONS] <PACKAGE>")
 		os.Exit(2)
 	}
 	if err := set.Parse(args); err != nil {
 		return err
 	}
-	err := build.CheckPackage(set.Arg(0))
-	if err == nil {
-		fmt.Fprintf(os.Stdout, "%s: package is valid", set.Arg(0))
-		fmt.Fprintln(os.Stdout)
-	}
-	return err
+	return build.Verify(set.Arg(0), *keyring, *output, os.Stdout)
 }
 
 func decodePackage(context string, config *packfile.DecoderConfig) (*packfile.Package, error) {
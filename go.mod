@@ -3,8 +3,16 @@ module github.com/midbel/packit
 go 1.23.0
 
 require (
+	github.com/ProtonMail/go-crypto v1.1.6
+	github.com/klauspost/compress v1.17.11
 	github.com/midbel/distance v0.1.0
 	github.com/midbel/shlex v0.2.3
 	github.com/midbel/tape v0.2.5
 	github.com/midbel/textwrap v0.3.0
 )
+
+require (
+	github.com/cloudflare/circl v1.3.7 // indirect
+	golang.org/x/crypto v0.17.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+)
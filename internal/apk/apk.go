@@ -0,0 +1,477 @@
+package apk
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+const (
+	pkgInfoFile    = ".PKGINFO"
+	preInstFile    = ".pre-install"
+	postInstFile   = ".post-install"
+	preDeinstFile  = ".pre-deinstall"
+	postDeinstFile = ".post-deinstall"
+	signPrefix     = ".SIGN.RSA."
+	checksumRecord = "APK-TOOLS.checksum.SHA1"
+)
+
+const (
+	EnvPrivateKey = "PACKAGER_PRIVKEY"
+	KeysDir       = "/etc/apk/keys"
+)
+
+//go:embed templates/pkginfo.tpl
+var pkginfoFile string
+
+type ApkBuilder struct {
+	writer    io.Writer
+	buildTime time.Time
+
+	key     *rsa.PrivateKey
+	keyName string
+}
+
+func Build(w io.Writer) (*ApkBuilder, error) {
+	b := ApkBuilder{
+		writer:    w,
+		buildTime: time.Now(),
+	}
+	return &b, nil
+}
+
+func (b *ApkBuilder) SignWith(file, _ string) error {
+	key, err := readPrivateKey(file)
+	if err != nil {
+		return err
+	}
+	b.key = key
+	b.keyName = filepath.Base(file) + ".pub"
+	return nil
+}
+
+func (b *ApkBuilder) SetSourceDateEpoch(when time.Time) {
+	b.buildTime = when
+}
+
+func (b *ApkBuilder) Build(p *packfile.Package) error {
+	if err := b.setup(p); err != nil {
+		return err
+	}
+	if err := b.build(p); err != nil {
+		return err
+	}
+	return b.teardown(p)
+}
+
+func (b *ApkBuilder) setup(pkg *packfile.Package) error {
+	if pkg.Setup == "" {
+		return nil
+	}
+	scan := bufio.NewScanner(strings.NewReader(pkg.Setup))
+	for scan.Scan() {
+		cmd := exec.Command("sh", "-c", scan.Text())
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *ApkBuilder) teardown(pkg *packfile.Package) error {
+	if pkg.Teardown == "" {
+		return nil
+	}
+	scan := bufio.NewScanner(strings.NewReader(pkg.Teardown))
+	for scan.Scan() {
+		cmd := exec.Command("sh", "-c", scan.Text())
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *ApkBuilder) build(p *packfile.Package) error {
+	dir, err := os.MkdirTemp("", "packit-apk-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := writeFiles(p, dir)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	sum := sha256.New()
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(sum, data); err != nil {
+		return err
+	}
+	ctrl, err := b.writeControl(p, fmt.Sprintf("%x", sum.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	if err := b.writeSignature(ctrl); err != nil {
+		return err
+	}
+	if _, err := b.writer.Write(ctrl); err != nil {
+		return err
+	}
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(b.writer, data)
+	return err
+}
+
+func (b *ApkBuilder) writeSignature(ctrl []byte) error {
+	if b.key == nil {
+		return nil
+	}
+	sum := sha1.Sum(ctrl)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, b.key, crypto.SHA1, sum[:])
+	if err != nil {
+		return err
+	}
+	return writeSegment(b.writer, func(w *tar.Writer) error {
+		h := makeTarHeader(signPrefix+b.keyName, len(sig), packfile.PermFile, b.buildTime)
+		if err := w.WriteHeader(h); err != nil {
+			return err
+		}
+		_, err := w.Write(sig)
+		return err
+	})
+}
+
+func (b *ApkBuilder) writeControl(p *packfile.Package, datahash string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := writeSegment(&buf, func(w *tar.Writer) error {
+		if err := b.writeInfo(w, p, datahash); err != nil {
+			return err
+		}
+		return writeScripts(w, p, b.buildTime)
+	})
+	return buf.Bytes(), err
+}
+
+func (b *ApkBuilder) writeInfo(w *tar.Writer, p *packfile.Package, datahash string) error {
+	fn := template.FuncMap{
+		"pkgver":     getPackageVersion,
+		"arch":       getPackageArch,
+		"dependency": formatDependency,
+	}
+	t, err := template.New("pkginfo").Funcs(fn).Parse(pkginfoFile)
+	if err != nil {
+		return err
+	}
+	ctx := struct {
+		*packfile.Package
+		BuildDate int64
+		DataHash  string
+	}{
+		Package:   p,
+		BuildDate: b.buildTime.Unix(),
+		DataHash:  datahash,
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, ctx); err != nil {
+		return err
+	}
+	h := makeTarHeader(pkgInfoFile, buf.Len(), packfile.PermFile, b.buildTime)
+	if err := w.WriteHeader(h); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, &buf)
+	return err
+}
+
+func writeScripts(w *tar.Writer, pkg *packfile.Package, when time.Time) error {
+	list := []struct {
+		Script string
+		File   string
+	}{
+		{
+			Script: pkg.PreInst,
+			File:   preInstFile,
+		},
+		{
+			Script: pkg.PostInst,
+			File:   postInstFile,
+		},
+		{
+			Script: pkg.PreRem,
+			File:   preDeinstFile,
+		},
+		{
+			Script: pkg.PostRem,
+			File:   postDeinstFile,
+		},
+	}
+	for _, i := range list {
+		if i.Script == "" {
+			continue
+		}
+		h := makeTarHeader(i.File, len(i.Script), packfile.PermExec, when)
+		if err := w.WriteHeader(h); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, i.Script); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSegment(w io.Writer, do func(*tar.Writer) error) error {
+	z, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+	tw := tar.NewWriter(z)
+	if err := do(tw); err != nil {
+		return err
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+func writeFiles(pkg *packfile.Package, tmpdir string) (*os.File, error) {
+	f, err := os.Create(filepath.Join(tmpdir, "data.tar.gz"))
+	if err != nil {
+		return nil, err
+	}
+	ws, _ := gzip.NewWriterLevel(f, gzip.BestCompression)
+	w := tar.NewWriter(ws)
+
+	slices.SortFunc(pkg.Files, func(a, b packfile.Resource) int {
+		return strings.Compare(a.Target, b.Target)
+	})
+	seen := make(map[string]struct{})
+	for i, r := range pkg.Files {
+		if r.Target == "" {
+			continue
+		}
+		dir := filepath.Dir(r.Target)
+		if _, ok := seen[dir]; len(dir) > 0 && !ok {
+			paths := strings.Split(dir, string(filepath.Separator))
+			for i := range paths {
+				if paths[i] == "" || paths[i] == "." {
+					continue
+				}
+				target := strings.Join(paths[:i+1], "/")
+				if _, ok := seen[target]; ok {
+					continue
+				}
+				seen[target] = struct{}{}
+				h := makeTarHeader(target, 0, packfile.PermDir, r.Lastmod)
+				h.Typeflag = tar.TypeDir
+				if err := w.WriteHeader(h); err != nil {
+					f.Close()
+					return nil, err
+				}
+			}
+		}
+		if r.IsDirectory() {
+			if _, ok := seen[r.Target]; ok {
+				continue
+			}
+			seen[r.Target] = struct{}{}
+			perm := r.Perm
+			if perm == 0 {
+				perm = packfile.PermDir
+			}
+			h := makeTarHeader(r.Target, 0, int(perm), r.Lastmod)
+			h.Typeflag = tar.TypeDir
+			if err := w.WriteHeader(h); err != nil {
+				f.Close()
+				return nil, err
+			}
+			continue
+		}
+		if r.IsSymlink() {
+			h := makeTarHeader(r.Target, 0, 0o777, r.Lastmod)
+			h.Typeflag = tar.TypeSymlink
+			h.Linkname = r.Link
+			if err := w.WriteHeader(h); err != nil {
+				f.Close()
+				return nil, err
+			}
+			continue
+		}
+		rs, sum, err := readResource(r)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		perm := r.Perm
+		if perm == 0 {
+			perm = packfile.GetPermissionFromPath(r.Target)
+		}
+		h := makeTarHeader(r.Target, int(r.Size), int(perm), r.Lastmod)
+		h.Format = tar.FormatPAX
+		h.PAXRecords = map[string]string{
+			checksumRecord: fmt.Sprintf("%x", sum.Sum(nil)),
+		}
+		if err := w.WriteHeader(h); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if _, err := io.Copy(w, rs); err != nil {
+			f.Close()
+			return nil, err
+		}
+		r.Local.Close()
+		pkg.Files[i] = r
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := ws.Close(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func readResource(r packfile.Resource) (io.Reader, hash.Hash, error) {
+	sum := sha1.New()
+	if s, ok := r.Local.(io.ReadSeeker); ok {
+		if _, err := io.Copy(sum, s); err != nil {
+			return nil, nil, err
+		}
+		_, err := s.Seek(0, io.SeekStart)
+		return s, sum, err
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(io.MultiWriter(&buf, sum), r.Local); err != nil {
+		return nil, nil, err
+	}
+	return &buf, sum, nil
+}
+
+func readEnvPrivateKey() (*rsa.PrivateKey, string, error) {
+	file := os.Getenv(EnvPrivateKey)
+	if file == "" {
+		return nil, "", nil
+	}
+	key, err := readPrivateKey(file)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, filepath.Base(file) + ".pub", nil
+}
+
+func readPrivateKey(file string) (*rsa.PrivateKey, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", file)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rk, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: RSA private key expected", file)
+	}
+	return rk, nil
+}
+
+func makeTarHeader(file string, size, perm int, when time.Time) *tar.Header {
+	if when.IsZero() {
+		when = time.Now()
+	}
+	h := tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     file,
+		Size:     int64(size),
+		Uid:      0,
+		Gid:      0,
+		Uname:    packfile.DefaultUser,
+		Gname:    packfile.DefaultGroup,
+		ModTime:  when.Truncate(time.Second),
+		Mode:     int64(perm),
+	}
+	return &h
+}
+
+func getPackageVersion(pkg *packfile.Package) string {
+	release := pkg.Release
+	if release == "" {
+		release = "0"
+	}
+	return fmt.Sprintf("%s-r%s", pkg.Version, strings.TrimPrefix(release, "r"))
+}
+
+func getPackageArch(arch string) string {
+	switch arch {
+	case packfile.Arch64:
+		return "x86_64"
+	case packfile.Arch32:
+		return "x86"
+	case "arm64":
+		return "aarch64"
+	case packfile.ArchAll, "":
+		return packfile.ArchNo
+	default:
+		return arch
+	}
+}
+
+func formatDependency(dp packfile.Dependency) string {
+	if dp.Version == "" {
+		return dp.Package
+	}
+	return dp.Package + formatDependencyConstraint(dp.Constraint) + dp.Version
+}
+
+func formatDependencyConstraint(op string) string {
+	switch op {
+	case packfile.ConstraintEq:
+		op = "="
+	case packfile.ConstraintGt:
+		op = ">"
+	case packfile.ConstraintGe, "":
+		op = ">="
+	case packfile.ConstraintLt:
+		op = "<"
+	case packfile.ConstraintLe:
+		op = "<="
+	default:
+		op = "="
+	}
+	return op
+}
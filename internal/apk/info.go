@@ -0,0 +1,196 @@
+package apk
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/tape"
+)
+
+func Content(file string) ([]*tape.Header, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	rs := newReader(r)
+	if _, err := readControl(rs, nil); err != nil {
+		return nil, err
+	}
+	dt, err := rs.Next(nil)
+	if err != nil {
+		return nil, err
+	}
+	var list []*tape.Header
+	for {
+		h, err := dt.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if _, err := io.Copy(io.Discard, io.LimitReader(dt, h.Size)); err != nil {
+			return nil, err
+		}
+		hdr := tape.Header{
+			Filename: h.Name,
+			Size:     h.Size,
+			Mode:     h.Mode,
+			Uid:      int64(h.Uid),
+			Gid:      int64(h.Gid),
+			ModTime:  h.ModTime,
+		}
+		if h.Typeflag == tar.TypeDir {
+			hdr.Mode |= int64(os.ModeDir)
+		}
+		list = append(list, &hdr)
+	}
+	return list, nil
+}
+
+type PackageInfo struct {
+	packfile.Package
+
+	Size      int64
+	BuildTime time.Time
+	DataHash  string
+	Origin    string
+	Signature string
+}
+
+func Info(file string) (*PackageInfo, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	ctrl, err := readControl(newReader(r), nil)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := parseInfo(bytes.NewReader(ctrl.Files[pkgInfoFile]))
+	if err != nil {
+		return nil, err
+	}
+	pkg.Signature = ctrl.KeyName
+	return pkg, nil
+}
+
+func parseInfo(r io.Reader) (*PackageInfo, error) {
+	var (
+		scan = bufio.NewScanner(r)
+		pkg  PackageInfo
+	)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		field, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(field) {
+		case "pkgname":
+			pkg.Name = value
+		case "pkgver":
+			pkg.Version = value
+			if ix := strings.LastIndex(value, "-r"); ix > 0 {
+				pkg.Version = value[:ix]
+				pkg.Release = value[ix+2:]
+			}
+		case "pkgdesc":
+			pkg.Summary = value
+		case "url":
+			pkg.Home = value
+		case "builddate":
+			unix, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			pkg.BuildTime = time.Unix(unix, 0)
+		case "packager", "maintainer":
+			pkg.Maintainer = packfile.ParseMaintainer(value)
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			pkg.Size = size
+		case "arch":
+			pkg.Arch = value
+		case "origin":
+			pkg.Origin = value
+		case "license":
+			pkg.License = value
+		case "depend":
+			dep := parseDependency(value)
+			dep.Type = "depends"
+			if name, ok := strings.CutPrefix(value, "!"); ok {
+				dep.Package = name
+				dep.Type = "conflicts"
+			}
+			pkg.Depends = append(pkg.Depends, dep)
+		case "provides", "replaces":
+			dep := parseDependency(value)
+			dep.Type = strings.TrimSpace(field)
+			pkg.Depends = append(pkg.Depends, dep)
+		case "datahash":
+			pkg.DataHash = value
+		default:
+		}
+	}
+	return &pkg, scan.Err()
+}
+
+func parseDependency(str string) packfile.Dependency {
+	var dep packfile.Dependency
+	ix := strings.IndexAny(str, "<>=~")
+	if ix < 0 {
+		dep.Package = str
+		return dep
+	}
+	dep.Package = str[:ix]
+	op := str[ix:]
+	jx := strings.IndexFunc(op, func(r rune) bool {
+		return !strings.ContainsRune("<>=~", r)
+	})
+	if jx < 0 {
+		return dep
+	}
+	dep.Version = op[jx:]
+	switch op[:jx] {
+	case "=", "~":
+		dep.Constraint = packfile.ConstraintEq
+	case ">":
+		dep.Constraint = packfile.ConstraintGt
+	case ">=":
+		dep.Constraint = packfile.ConstraintGe
+	case "<":
+		dep.Constraint = packfile.ConstraintLt
+	case "<=":
+		dep.Constraint = packfile.ConstraintLe
+	default:
+	}
+	return dep
+}
+
+func Dependencies(file string) ([]packfile.Dependency, error) {
+	pkg, err := Info(file)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.Depends, nil
+}
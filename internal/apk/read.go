@@ -0,0 +1,140 @@
+package apk
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+type segmentReader struct {
+	inner *bufio.Reader
+	sum   hash.Hash
+}
+
+func (r *segmentReader) Read(b []byte) (int, error) {
+	n, err := r.inner.Read(b)
+	if r.sum != nil && n > 0 {
+		r.sum.Write(b[:n])
+	}
+	return n, err
+}
+
+func (r *segmentReader) ReadByte() (byte, error) {
+	c, err := r.inner.ReadByte()
+	if r.sum != nil && err == nil {
+		r.sum.Write([]byte{c})
+	}
+	return c, err
+}
+
+type reader struct {
+	inner *segmentReader
+	z     *gzip.Reader
+}
+
+func newReader(r io.Reader) *reader {
+	rs := segmentReader{
+		inner: bufio.NewReader(r),
+	}
+	return &reader{
+		inner: &rs,
+	}
+}
+
+func (r *reader) Next(sum hash.Hash) (*tar.Reader, error) {
+	if r.z != nil {
+		if _, err := io.Copy(io.Discard, r.z); err != nil {
+			return nil, err
+		}
+	}
+	r.inner.sum = sum
+	if r.z == nil {
+		z, err := gzip.NewReader(r.inner)
+		if err != nil {
+			return nil, err
+		}
+		r.z = z
+	} else if err := r.z.Reset(r.inner); err != nil {
+		return nil, err
+	}
+	r.z.Multistream(false)
+	return tar.NewReader(r.z), nil
+}
+
+func (r *reader) Close() error {
+	if r.z == nil {
+		return nil
+	}
+	if _, err := io.Copy(io.Discard, r.z); err != nil {
+		return err
+	}
+	r.inner.sum = nil
+	return r.z.Close()
+}
+
+type control struct {
+	Signature []byte
+	KeyName   string
+	Files     map[string][]byte
+}
+
+func readControl(r *reader, sum hash.Hash) (*control, error) {
+	files, err := readSegment(r, sum)
+	if err != nil {
+		return nil, err
+	}
+	var ctrl control
+	for name, buf := range files {
+		if !strings.HasPrefix(name, signPrefix) {
+			continue
+		}
+		ctrl.KeyName = strings.TrimPrefix(name, signPrefix)
+		ctrl.Signature = buf
+	}
+	if ctrl.Signature != nil {
+		if sum != nil {
+			sum.Reset()
+		}
+		files, err = readSegment(r, sum)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := files[pkgInfoFile]; !ok {
+		return nil, fmt.Errorf("%s: file not found in control segment", pkgInfoFile)
+	}
+	ctrl.Files = files
+	return &ctrl, nil
+}
+
+func readSegment(r *reader, sum hash.Hash) (map[string][]byte, error) {
+	rs, err := r.Next(sum)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for {
+		h, err := rs.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		var tmp bytes.Buffer
+		if _, err := io.Copy(&tmp, io.LimitReader(rs, h.Size)); err != nil {
+			return nil, err
+		}
+		files[h.Name] = tmp.Bytes()
+	}
+	if _, err := io.Copy(io.Discard, r.z); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
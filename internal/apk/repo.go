@@ -0,0 +1,195 @@
+package apk
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+const (
+	indexFile    = "APKINDEX"
+	indexArchive = "APKINDEX.tar.gz"
+	descFile     = "DESCRIPTION"
+)
+
+type indexEntry struct {
+	*PackageInfo
+	Checksum []byte
+	Length   int64
+}
+
+func WriteRepository(dir string, when time.Time) error {
+	groups := make(map[string][]*indexEntry)
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(file) != ".apk" {
+			return nil
+		}
+		e, err := readIndexEntry(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		parent := filepath.Dir(file)
+		groups[parent] = append(groups[parent], e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("%s: no apk packages found", dir)
+	}
+	key, name, err := readEnvPrivateKey()
+	if err != nil {
+		return err
+	}
+	for parent, list := range groups {
+		slices.SortFunc(list, func(a, b *indexEntry) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		if err := writeIndex(parent, list, key, name, when); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeIndex(dir string, list []*indexEntry, key *rsa.PrivateKey, name string, when time.Time) error {
+	var index bytes.Buffer
+	for _, e := range list {
+		writeIndexEntry(&index, e)
+	}
+	var (
+		body bytes.Buffer
+		desc = filepath.Base(dir)
+	)
+	z, _ := gzip.NewWriterLevel(&body, gzip.BestCompression)
+	tw := tar.NewWriter(z)
+	for _, f := range []struct {
+		Name string
+		Data []byte
+	}{
+		{Name: descFile, Data: []byte(desc)},
+		{Name: indexFile, Data: index.Bytes()},
+	} {
+		h := makeTarHeader(f.Name, len(f.Data), packfile.PermFile, when)
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := z.Close(); err != nil {
+		return err
+	}
+	var archive bytes.Buffer
+	if key != nil {
+		sum := sha1.Sum(body.Bytes())
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, sum[:])
+		if err != nil {
+			return err
+		}
+		err = writeSegment(&archive, func(w *tar.Writer) error {
+			h := makeTarHeader(signPrefix+name, len(sig), packfile.PermFile, when)
+			if err := w.WriteHeader(h); err != nil {
+				return err
+			}
+			_, err := w.Write(sig)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	archive.Write(body.Bytes())
+	return packfile.WriteFile(filepath.Join(dir, indexArchive), archive.Bytes())
+}
+
+func writeIndexEntry(w io.Writer, e *indexEntry) {
+	writeIndexField(w, "C", "Q1"+base64.StdEncoding.EncodeToString(e.Checksum))
+	writeIndexField(w, "P", e.Name)
+	writeIndexField(w, "V", getPackageVersion(&e.Package))
+	writeIndexField(w, "A", e.Arch)
+	writeIndexField(w, "S", fmt.Sprintf("%d", e.Length))
+	writeIndexField(w, "I", fmt.Sprintf("%d", e.Size))
+	writeIndexField(w, "T", e.Summary)
+	writeIndexField(w, "U", e.Home)
+	writeIndexField(w, "L", e.License)
+	writeIndexField(w, "o", e.Origin)
+	writeIndexField(w, "m", e.Maintainer.String())
+	writeIndexField(w, "t", fmt.Sprintf("%d", e.BuildTime.Unix()))
+
+	var depends, provides []string
+	for _, d := range e.Depends {
+		switch d.Type {
+		case "depends":
+			depends = append(depends, formatDependency(d))
+		case "conflicts":
+			depends = append(depends, "!"+d.Package)
+		case "provides":
+			provides = append(provides, formatDependency(d))
+		default:
+		}
+	}
+	writeIndexField(w, "D", strings.Join(depends, " "))
+	writeIndexField(w, "p", strings.Join(provides, " "))
+	fmt.Fprintln(w)
+}
+
+func writeIndexField(w io.Writer, field, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(w, "%s:%s", field, value)
+	fmt.Fprintln(w)
+}
+
+func readIndexEntry(file string) (*indexEntry, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var (
+		rs  = newReader(r)
+		sum = sha1.New()
+	)
+	ctrl, err := readControl(rs, sum)
+	if err != nil {
+		return nil, err
+	}
+	var e indexEntry
+	if e.PackageInfo, err = parseInfo(bytes.NewReader(ctrl.Files[pkgInfoFile])); err != nil {
+		return nil, err
+	}
+	e.Checksum = sum.Sum(nil)
+
+	s, err := r.Stat()
+	if err != nil {
+		return nil, err
+	}
+	e.Length = s.Size()
+	return &e, nil
+}
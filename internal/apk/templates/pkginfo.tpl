@@ -0,0 +1,27 @@
+# Generated by packit
+pkgname = {{.Name}}
+pkgver = {{pkgver .Package}}
+pkgdesc = {{.Summary}}
+{{with .Home}}url = {{.}}
+{{end -}}
+builddate = {{.BuildDate}}
+{{with .Maintainer.Name}}packager = {{$.Maintainer}}
+{{end -}}
+size = {{.TotalSize}}
+arch = {{arch .Arch}}
+origin = {{.Name}}
+{{with .Maintainer.Name}}maintainer = {{$.Maintainer}}
+{{end -}}
+{{with .License}}license = {{.}}
+{{end -}}
+{{range .Requires}}depend = {{dependency .}}
+{{end -}}
+{{range .Conflicts}}depend = !{{.Package}}
+{{end -}}
+{{range .Breaks}}depend = !{{.Package}}
+{{end -}}
+{{range .Provides}}provides = {{dependency .}}
+{{end -}}
+{{range .Replaces}}replaces = {{.Package}}
+{{end -}}
+datahash = {{.DataHash}}
@@ -0,0 +1,118 @@
+package apk
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func Check(file string) error {
+	r, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var (
+		rs  = newReader(r)
+		sum = sha1.New()
+	)
+	ctrl, err := readControl(rs, sum)
+	if err != nil {
+		return err
+	}
+	if err := checkSignature(ctrl, sum.Sum(nil)); err != nil {
+		return err
+	}
+	pkg, err := parseInfo(bytes.NewReader(ctrl.Files[pkgInfoFile]))
+	if err != nil {
+		return err
+	}
+	data := sha256.New()
+	if err := checkFiles(rs, data); err != nil {
+		return err
+	}
+	if err := rs.Close(); err != nil {
+		return err
+	}
+	if digest := hex.EncodeToString(data.Sum(nil)); pkg.DataHash != "" && digest != pkg.DataHash {
+		return fmt.Errorf("data: invalid sha256 checksum (%s != %s)", pkg.DataHash, digest)
+	}
+	return nil
+}
+
+func checkSignature(ctrl *control, sum []byte) error {
+	if ctrl.Signature == nil {
+		return nil
+	}
+	if ctrl.KeyName == "" || strings.Contains(ctrl.KeyName, "/") || strings.Contains(ctrl.KeyName, "..") {
+		return fmt.Errorf("%s: invalid key name", ctrl.KeyName)
+	}
+	buf, err := os.ReadFile(filepath.Join(KeysDir, ctrl.KeyName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s: public key not found in %s, signature can not be checked", ctrl.KeyName, KeysDir)
+		}
+		return err
+	}
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return fmt.Errorf("%s: no PEM data found", ctrl.KeyName)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%s: RSA public key expected", ctrl.KeyName)
+	}
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA1, sum, ctrl.Signature); err != nil {
+		return fmt.Errorf("control: invalid signature (%s)", ctrl.KeyName)
+	}
+	return nil
+}
+
+func checkFiles(r *reader, sum hash.Hash) error {
+	rs, err := r.Next(sum)
+	if err != nil {
+		return err
+	}
+	for {
+		h, err := rs.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		value, ok := h.PAXRecords[checksumRecord]
+		if !ok {
+			return fmt.Errorf("%s: file has no %s record", h.Name, checksumRecord)
+		}
+		digest := sha1.New()
+		if _, err := io.Copy(digest, io.LimitReader(rs, h.Size)); err != nil {
+			return err
+		}
+		if hex.EncodeToString(digest.Sum(nil)) != value {
+			return fmt.Errorf("%s: checksum mismatched (%s)", h.Name, value)
+		}
+	}
+	return nil
+}
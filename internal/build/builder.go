@@ -1,15 +1,23 @@
 package build
 
 import (
+	"archive/tar"
 	_ "embed"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"text/template"
+	"time"
 
+	"github.com/midbel/packit/internal/apk"
 	"github.com/midbel/packit/internal/deb"
 	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/pacman"
+	"github.com/midbel/packit/internal/pgp"
 	"github.com/midbel/packit/internal/rpm"
 	"github.com/midbel/tape"
 )
@@ -18,13 +26,30 @@ type Builder interface {
 	Build(*packfile.Package) error
 }
 
+type Signer interface {
+	SignWith(string, string) error
+}
+
+type Reproducer interface {
+	SetSourceDateEpoch(time.Time)
+}
+
 //go:embed templates/rpm_info.txt
 var rpmInfoFile string
 
 //go:embed templates/deb_info.txt
 var debInfoFile string
 
-func Info(file string, all, deps bool, w io.Writer) error {
+//go:embed templates/apk_info.txt
+var apkInfoFile string
+
+//go:embed templates/arch_info.txt
+var archInfoFile string
+
+func Info(file string, all, deps bool, format string, w io.Writer) error {
+	if !IsTextOutput(format) {
+		return writePackageInfo(file, deps && !all, format, w)
+	}
 	if deps && !all {
 		return getPackageDeps(file, w)
 	}
@@ -37,27 +62,98 @@ func Info(file string, all, deps bool, w io.Writer) error {
 	return nil
 }
 
+var dependencyTypes = []string{
+	"depends",
+	"recommends",
+	"suggests",
+	"enhances",
+	"breaks",
+	"conflicts",
+	"replaces",
+	"provides",
+}
+
 func getPackageDeps(file string, w io.Writer) error {
 	var (
-		list []string
+		list []packfile.Dependency
 		err  error
 	)
-	switch ext := filepath.Ext(file); ext {
-	case ".deb":
+	switch ext := getExtension(file); ext {
+	case ".deb", ".ipk":
 		list, err = deb.Dependencies(file)
 	case ".rpm":
 		list, err = rpm.Dependencies(file)
+	case ".apk":
+		list, err = apk.Dependencies(file)
+	case pacman.Extension:
+		list, err = pacman.Dependencies(file)
 	default:
 		return fmt.Errorf("%s: package type not supported", ext)
 	}
-	if err != nil || len(list) == 0 {
+	if err != nil {
 		return err
 	}
-	fmt.Fprintln(w, "Dependencies:")
+	printDependencies(list, w)
+	return nil
+}
+
+func printDependencies(list []packfile.Dependency, w io.Writer) {
+	if len(list) == 0 {
+		return
+	}
+	var (
+		groups = make(map[string][]packfile.Dependency)
+		types  = slices.Insert(slices.Clone(dependencyTypes), 1, "pre-depends")
+	)
 	for _, d := range list {
-		fmt.Fprintln(w, "- "+d)
+		if !slices.Contains(types, d.Type) {
+			types = append(types, d.Type)
+		}
+		groups[d.Type] = append(groups[d.Type], d)
+	}
+	fmt.Fprintln(w, "Dependencies:")
+	for _, t := range types {
+		if len(groups[t]) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:", t)
+		fmt.Fprintln(w)
+		for _, d := range groups[t] {
+			fmt.Fprintln(w, "- "+formatDependency(d))
+		}
+	}
+}
+
+func formatDependency(dep packfile.Dependency) string {
+	var str strings.Builder
+	str.WriteString(dep.Package)
+	if dep.Arch != "" {
+		str.WriteString(":" + dep.Arch)
+	}
+	if dep.Version != "" {
+		fmt.Fprintf(&str, " (%s %s)", formatDependencyConstraint(dep.Constraint), dep.Version)
+	}
+	for _, alt := range dep.Alternatives {
+		str.WriteString(" | " + formatDependency(alt))
+	}
+	return str.String()
+}
+
+func formatDependencyConstraint(op string) string {
+	switch op {
+	case packfile.ConstraintNe:
+		return "!="
+	case packfile.ConstraintGt:
+		return ">"
+	case packfile.ConstraintGe:
+		return ">="
+	case packfile.ConstraintLt:
+		return "<"
+	case packfile.ConstraintLe:
+		return "<="
+	default:
+		return "="
 	}
-	return nil
 }
 
 func getPackageInfos(file string, w io.Writer) error {
@@ -66,13 +162,19 @@ func getPackageInfos(file string, w io.Writer) error {
 		err  error
 		info string
 	)
-	switch ext := filepath.Ext(file); ext {
-	case ".deb":
+	switch ext := getExtension(file); ext {
+	case ".deb", ".ipk":
 		info = debInfoFile
 		pkg, err = deb.Info(file)
 	case ".rpm":
 		info = rpmInfoFile
 		pkg, err = rpm.Info(file)
+	case ".apk":
+		info = apkInfoFile
+		pkg, err = apk.Info(file)
+	case pacman.Extension:
+		info = archInfoFile
+		pkg, err = pacman.Info(file)
 	default:
 		return fmt.Errorf("%s: package type not supported", ext)
 	}
@@ -86,22 +188,111 @@ func getPackageInfos(file string, w io.Writer) error {
 	return tpl.Execute(w, pkg)
 }
 
-func Content(file string, w io.Writer) error {
+func writePackageInfo(file string, deps bool, format string, w io.Writer) error {
+	var doc PackageDocument
+	switch ext := getExtension(file); ext {
+	case ".deb", ".ipk":
+		info, err := deb.Info(file)
+		if err != nil {
+			return err
+		}
+		doc = makePackageDocument(file, strings.TrimPrefix(ext, "."), &info.Package)
+		doc.Size = info.Size
+		if err := readDebScripts(file, &doc.Scripts); err != nil {
+			return err
+		}
+	case ".rpm":
+		info, err := rpm.Info(file)
+		if err != nil {
+			return err
+		}
+		doc = makePackageDocument(file, packfile.Rpm, &info.Package)
+		doc.Size = info.Size
+		doc.ArchiveSize = info.ArchiveSize
+		doc.Epoch = info.Epoch
+		doc.SourceRpm = info.SourceRpm
+		doc.BuildTime = formatDocumentTime(info.BuildTime)
+		doc.BuildHost = info.BuildHost
+		doc.Compressor = info.Compressor
+	case ".apk":
+		info, err := apk.Info(file)
+		if err != nil {
+			return err
+		}
+		doc = makePackageDocument(file, packfile.Apk, &info.Package)
+		doc.Size = info.Size
+		doc.BuildTime = formatDocumentTime(info.BuildTime)
+		doc.DataHash = info.DataHash
+		doc.Origin = info.Origin
+		doc.Signature = info.Signature
+	case pacman.Extension:
+		info, err := pacman.Info(file)
+		if err != nil {
+			return err
+		}
+		doc = makePackageDocument(file, packfile.Pacman, &info.Package)
+		doc.Size = info.Size
+		doc.BuildTime = formatDocumentTime(info.BuildTime)
+		doc.Base = info.Base
+	default:
+		return fmt.Errorf("%s: package type not supported", ext)
+	}
+	if deps {
+		return WriteOutput(w, format, doc.Depends)
+	}
+	return WriteOutput(w, format, doc)
+}
+
+func readDebScripts(file string, scripts *ScriptsDocument) error {
+	return deb.ControlMembers(file, func(h *tar.Header, r io.Reader) error {
+		var str *string
+		switch path.Base(h.Name) {
+		case "preinst":
+			str = &scripts.PreInst
+		case "postinst":
+			str = &scripts.PostInst
+		case "prerm":
+			str = &scripts.PreRem
+		case "postrm":
+			str = &scripts.PostRem
+		default:
+			return nil
+		}
+		buf, err := io.ReadAll(r)
+		if err == nil {
+			*str = string(buf)
+		}
+		return err
+	})
+}
+
+func Content(file, format string, w io.Writer) error {
 	var (
 		list []*tape.Header
 		err  error
 	)
-	switch ext := filepath.Ext(file); ext {
-	case ".deb":
+	switch ext := getExtension(file); ext {
+	case ".deb", ".ipk":
 		list, err = deb.Content(file)
 	case ".rpm":
 		list, err = rpm.Content(file)
+	case ".apk":
+		list, err = apk.Content(file)
+	case pacman.Extension:
+		list, err = pacman.Content(file)
 	default:
 		return fmt.Errorf("%s: package type not supported", ext)
 	}
-	if err != nil || len(list) == 0 {
+	if err != nil {
 		return err
 	}
+	if !IsTextOutput(format) {
+		files := []FileDocument{}
+		for _, h := range list {
+			files = append(files, makeFileDocument(h))
+		}
+		return WriteOutput(w, format, files)
+	}
 	for _, h := range list {
 		when := h.ModTime.Format("Jan 02 15:04")
 		fmt.Fprintf(w, "%s %-8s %-8s %8d %s %s", os.FileMode(h.Mode), h.User(), h.Group(), h.Size, when, h.Filename)
@@ -110,35 +301,88 @@ func Content(file string, w io.Writer) error {
 	return nil
 }
 
-func CheckPackage(file string) error {
-	switch ext := filepath.Ext(file); ext {
-	case ".deb":
-		return deb.Check(file)
+func Verify(file, keyring, format string, w io.Writer) error {
+	err := CheckPackage(file, keyring)
+	if IsTextOutput(format) {
+		if err == nil {
+			fmt.Fprintf(w, "%s: package is valid", file)
+			fmt.Fprintln(w)
+		}
+		return err
+	}
+	doc := VerifyDocument{
+		File:   file,
+		Valid:  err == nil,
+		Errors: []string{},
+	}
+	if err != nil {
+		doc.Errors = append(doc.Errors, err.Error())
+	}
+	if err := WriteOutput(w, format, doc); err != nil {
+		return err
+	}
+	return err
+}
+
+func CheckPackage(file, keyring string) error {
+	var (
+		keys *pgp.Keyring
+		err  error
+	)
+	if keyring != "" {
+		if keys, err = pgp.ReadKeyring(keyring); err != nil {
+			return err
+		}
+	}
+	switch ext := getExtension(file); ext {
+	case ".deb", ".ipk":
+		return deb.Check(file, keys)
 	case ".rpm":
-		return rpm.Check(file)
+		return rpm.Check(file, keys)
+	case ".apk":
+		return apk.Check(file)
+	case pacman.Extension:
+		return pacman.Check(file)
 	default:
 		return fmt.Errorf("%s: package type not supported", ext)
 	}
 }
 
 type PackageBuilder struct {
-	File      string
-	Dist      string
-	Type      string
-	OnlyDocs  bool
-	SplitDocs bool
+	File         string
+	Dist         string
+	Type         string
+	Arch         string
+	OnlyDocs     bool
+	SplitDocs    bool
+	DebugPackage bool
+	Reproducible bool
+	SignKey      string
+	SignRole     string
 }
 
 func (b *PackageBuilder) BuildPackage(context string) error {
 	if context == "" {
 		context = filepath.Dir(b.File)
 	}
-	pkg, err := packfile.Load(b.File, context)
+	pkg, err := packfile.LoadTarget(b.File, context, b.Type, b.Arch)
 	if err != nil {
 		return err
 	}
+	return b.buildTarget(pkg)
+}
 
-	var all []*packfile.Package
+func (b *PackageBuilder) buildTarget(pkg *packfile.Package) error {
+	var (
+		all   []*packfile.Package
+		debug *packfile.Package
+		err   error
+	)
+	if b.DebugPackage && !b.OnlyDocs {
+		if debug, err = splitDebug(b.Type, pkg); err != nil {
+			return err
+		}
+	}
 	if b.OnlyDocs {
 		all = append(all, pkg.OnlyDocs())
 	} else if b.SplitDocs {
@@ -146,6 +390,9 @@ func (b *PackageBuilder) BuildPackage(context string) error {
 	} else {
 		all = append(all, pkg)
 	}
+	if debug != nil {
+		all = append(all, debug)
+	}
 
 	for _, pkg := range all {
 		if err := b.buildPackage(pkg); err != nil {
@@ -156,25 +403,85 @@ func (b *PackageBuilder) BuildPackage(context string) error {
 }
 
 func (b *PackageBuilder) buildPackage(pkg *packfile.Package) error {
-	name := fmt.Sprintf("%s.%s", pkg.PackageName(), b.Type)
-	name = filepath.Join(b.Dist, name)
+	if epoch, ok := b.sourceDateEpoch(); ok {
+		for i := range pkg.Files {
+			pkg.Files[i].Lastmod = packfile.ClampTime(pkg.Files[i].Lastmod, epoch)
+		}
+	}
+	if err := stripFiles(pkg); err != nil {
+		return err
+	}
+	if err := resolveDepends(b.Type, pkg); err != nil {
+		return err
+	}
+	name := b.getPackageFile(b.Type, b.Arch, pkg)
 	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
 		return err
 	}
-	w, err := os.Create(name)
+	w, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
 	if err != nil {
 		return err
 	}
-	defer w.Close()
+	defer func() {
+		w.Close()
+		os.Remove(w.Name())
+	}()
+	if err := b.writePackage(pkg, w); err != nil {
+		return err
+	}
+	if err := w.Chmod(packfile.PermFile); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return os.Rename(w.Name(), name)
+}
 
+func (b *PackageBuilder) sourceDateEpoch() (time.Time, bool) {
+	if epoch, ok := packfile.SourceDateEpoch(); ok {
+		return epoch, ok
+	}
+	if b.Reproducible {
+		return time.Unix(0, 0).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+func (b *PackageBuilder) getPackageFile(kind, arch string, pkg *packfile.Package) string {
+	name := pkg.PackageName()
+	if arch != "" {
+		name += "." + pkg.Arch
+	}
+	return filepath.Join(b.Dist, name+getPackageExtension(kind))
+}
+
+func (b *PackageBuilder) writePackage(pkg *packfile.Package, w io.Writer) error {
 	builder, err := Build(b.Type, w)
 	if err != nil {
 		return err
 	}
+	if epoch, ok := b.sourceDateEpoch(); ok {
+		if r, ok := builder.(Reproducer); ok {
+			r.SetSourceDateEpoch(epoch)
+		}
+	}
+	if b.SignKey != "" {
+		s, ok := builder.(Signer)
+		if !ok {
+			return fmt.Errorf("%s: package signing not supported", b.Type)
+		}
+		if err := s.SignWith(b.SignKey, b.SignRole); err != nil {
+			return err
+		}
+	}
+	if err := builder.Build(pkg); err != nil {
+		return err
+	}
 	if c, ok := builder.(io.Closer); ok {
-		defer c.Close()
+		return c.Close()
 	}
-	return builder.Build(pkg)
+	return nil
 }
 
 func Build(kind string, w io.Writer) (Builder, error) {
@@ -183,7 +490,27 @@ func Build(kind string, w io.Writer) (Builder, error) {
 		return deb.Build(w)
 	case packfile.Rpm:
 		return rpm.Build(w)
+	case packfile.Apk:
+		return apk.Build(w)
+	case packfile.Ipk:
+		return deb.BuildIpk(w)
+	case packfile.Pacman:
+		return pacman.Build(w)
 	default:
 		return nil, fmt.Errorf("%s: package type not supported", kind)
 	}
 }
+
+func getExtension(file string) string {
+	if strings.HasSuffix(file, pacman.Extension) {
+		return pacman.Extension
+	}
+	return filepath.Ext(file)
+}
+
+func getPackageExtension(kind string) string {
+	if kind == packfile.Pacman {
+		return pacman.Extension
+	}
+	return "." + kind
+}
@@ -0,0 +1,88 @@
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/midbel/packit/internal/deb"
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/rpm"
+)
+
+var archNames = [][]string{
+	{packfile.Arch64, "x86_64"},
+	{packfile.Arch32, "i686"},
+	{"arm64", "aarch64"},
+	{"armhf", "armv7hl"},
+	{"ppc64el", "ppc64le"},
+	{packfile.ArchAll, packfile.ArchNo},
+}
+
+func Load(file string) (*packfile.Package, error) {
+	switch ext := getExtension(file); ext {
+	case ".deb", ".ipk":
+		return deb.Load(file)
+	case ".rpm":
+		return rpm.Load(file)
+	default:
+		return nil, fmt.Errorf("%s: package type not supported", ext)
+	}
+}
+
+func Convert(file, kind, dist string) error {
+	if ext := strings.TrimPrefix(filepath.Ext(file), "."); ext == kind {
+		return fmt.Errorf("%s: package is already a %s package", file, kind)
+	}
+	pkg, err := Load(file)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case packfile.Deb:
+		convertToDeb(pkg)
+	case packfile.Rpm:
+		convertToRpm(pkg)
+	default:
+		return fmt.Errorf("%s: package type not supported", kind)
+	}
+	b := PackageBuilder{
+		Type: kind,
+		Dist: dist,
+	}
+	return b.buildPackage(pkg)
+}
+
+func convertToDeb(pkg *packfile.Package) {
+	if pkg.Release != "" {
+		pkg.Version = fmt.Sprintf("%s-%s", pkg.Version, pkg.Release)
+		pkg.Release = ""
+	}
+	pkg.Arch = convertArch(pkg.Arch, 1, 0)
+	pkg.Depends = slices.DeleteFunc(pkg.Depends, func(d packfile.Dependency) bool {
+		return strings.HasPrefix(d.Package, "/") || strings.Contains(d.Package, "(")
+	})
+}
+
+func convertToRpm(pkg *packfile.Package) {
+	if ix := strings.LastIndex(pkg.Version, "-"); ix > 0 {
+		pkg.Release = pkg.Version[ix+1:]
+		pkg.Version = pkg.Version[:ix]
+	}
+	pkg.Arch = convertArch(pkg.Arch, 0, 1)
+	for i := range pkg.Depends {
+		if pkg.Depends[i].Type == "pre-depends" {
+			pkg.Depends[i].Type = "depends"
+		}
+	}
+}
+
+func convertArch(arch string, from, to int) string {
+	for _, names := range archNames {
+		if names[from] == arch {
+			return names[to]
+		}
+	}
+	return arch
+}
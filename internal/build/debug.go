@@ -0,0 +1,78 @@
+package build
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"path"
+
+	"github.com/midbel/packit/internal/elf"
+	"github.com/midbel/packit/internal/packfile"
+)
+
+const debugDir = "usr/lib/debug/.build-id"
+
+func splitDebug(kind string, pkg *packfile.Package) (*packfile.Package, error) {
+	var files []packfile.Resource
+	for i, r := range pkg.Files {
+		rs, ok := r.Local.(io.ReaderAt)
+		if !ok || !elf.IsElf(rs) {
+			continue
+		}
+		f, err := elf.NewFile(rs)
+		if err != nil {
+			continue
+		}
+		id := f.BuildID()
+		if len(id) < 3 || f.Stripped() {
+			continue
+		}
+		var debug, stripped bytes.Buffer
+		if err := f.KeepDebug(&debug); err != nil {
+			if errors.Is(err, elf.ErrStrip) {
+				continue
+			}
+			return nil, err
+		}
+		name := id[2:] + ".debug"
+		if err := f.StripWithLink(&stripped, name, crc32.ChecksumIEEE(debug.Bytes())); err != nil {
+			if errors.Is(err, elf.ErrStrip) {
+				continue
+			}
+			return nil, err
+		}
+		r.Local.Close()
+
+		pkg.Files[i].Local = memFile{bytes.NewReader(stripped.Bytes())}
+		pkg.Files[i].Size = int64(stripped.Len())
+		pkg.Files[i].Hash = ""
+
+		res := packfile.Resource{
+			Path:    r.Path,
+			Local:   memFile{bytes.NewReader(debug.Bytes())},
+			Target:  path.Join(debugDir, id[:2], name),
+			Perm:    packfile.PermFile,
+			Size:    int64(debug.Len()),
+			Lastmod: r.Lastmod,
+		}
+		files = append(files, res)
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	return pkg.Debug(getDebugSuffix(kind), files), nil
+}
+
+func getDebugSuffix(kind string) string {
+	switch kind {
+	case packfile.Deb:
+		return "-dbgsym"
+	case packfile.Rpm:
+		return "-debuginfo"
+	case packfile.Pacman:
+		return "-debug"
+	default:
+		return "-dbg"
+	}
+}
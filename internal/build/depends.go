@@ -0,0 +1,107 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/midbel/packit/internal/deb"
+	"github.com/midbel/packit/internal/elf"
+	"github.com/midbel/packit/internal/packfile"
+)
+
+type library struct {
+	Name string
+	Is64 bool
+}
+
+func resolveDepends(kind string, pkg *packfile.Package) error {
+	if !pkg.AutoDepends {
+		return nil
+	}
+	libs, err := collectLibraries(pkg)
+	if err != nil || len(libs) == 0 {
+		return err
+	}
+	var deps []packfile.Dependency
+	switch kind {
+	case packfile.Deb, packfile.Ipk:
+		var names []string
+		for _, i := range libs {
+			names = append(names, i.Name)
+		}
+		deps, err = deb.ResolveLibs(pkg.Shlibs, names)
+		if err != nil {
+			return err
+		}
+	case packfile.Rpm:
+		for _, i := range libs {
+			name := i.Name + "()"
+			if i.Is64 {
+				name += "(64bit)"
+			}
+			deps = append(deps, packfile.Dependency{
+				Package: name,
+				Type:    "depends",
+			})
+		}
+	case packfile.Apk:
+		for _, i := range libs {
+			deps = append(deps, packfile.Dependency{
+				Package: "so:" + i.Name,
+				Type:    "depends",
+			})
+		}
+	default:
+	}
+	for _, d := range deps {
+		ok := slices.ContainsFunc(pkg.Depends, func(other packfile.Dependency) bool {
+			return other.Type == d.Type && other.Package == d.Package
+		})
+		if !ok {
+			pkg.Depends = append(pkg.Depends, d)
+		}
+	}
+	return nil
+}
+
+func collectLibraries(pkg *packfile.Package) ([]library, error) {
+	var (
+		list     []library
+		provided = make(map[string]struct{})
+	)
+	for _, r := range pkg.Files {
+		rs, ok := r.Local.(io.ReaderAt)
+		if !ok || !elf.IsElf(rs) {
+			continue
+		}
+		f, err := elf.NewFile(rs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: skipped for automatic dependencies: %s", r.Target, err)
+			fmt.Fprintln(os.Stderr)
+			continue
+		}
+		if name := f.Soname(); name != "" {
+			provided[name] = struct{}{}
+		}
+		for _, name := range f.Libs() {
+			lib := library{
+				Name: name,
+				Is64: f.Is64(),
+			}
+			if !slices.Contains(list, lib) {
+				list = append(list, lib)
+			}
+		}
+	}
+	list = slices.DeleteFunc(list, func(i library) bool {
+		_, ok := provided[i.Name]
+		return ok
+	})
+	slices.SortFunc(list, func(a, b library) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return list, nil
+}
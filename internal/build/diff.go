@@ -0,0 +1,402 @@
+package build
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+const (
+	DiffMetadata  = "metadata"
+	DiffDepends   = "depends"
+	DiffFiles     = "files"
+	DiffConffiles = "conffiles"
+	DiffScripts   = "scripts"
+)
+
+type DiffEntry struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Old     string `json:"old,omitempty"`
+	New     string `json:"new,omitempty"`
+}
+
+type PackageDiff struct {
+	Old     string      `json:"old"`
+	New     string      `json:"new"`
+	Changes []DiffEntry `json:"changes"`
+}
+
+func Diff(old, new string) (*PackageDiff, error) {
+	prev, err := loadDiffState(old)
+	if err != nil {
+		return nil, err
+	}
+	next, err := loadDiffState(new)
+	if err != nil {
+		return nil, err
+	}
+	diff := PackageDiff{
+		Old:     old,
+		New:     new,
+		Changes: []DiffEntry{},
+	}
+	same := prev.kind == next.kind
+	for _, f := range diffFields {
+		if !f.common && !same {
+			continue
+		}
+		diff.compare(DiffMetadata, f.name, f.get(prev), f.get(next))
+	}
+	diff.compareSets(DiffDepends, prev.depends, next.depends)
+	for _, name := range mergeKeys(prev.files, next.files) {
+		a, b := prev.files[name], next.files[name]
+		if !same && (strings.HasPrefix(a, "dir") || strings.HasPrefix(b, "dir")) {
+			continue
+		}
+		diff.compare(DiffFiles, name, a, b)
+	}
+	diff.compareSets(DiffConffiles, prev.conffiles, next.conffiles)
+	for _, name := range []string{"preinst", "postinst", "prerm", "postrm", "check"} {
+		diff.compare(DiffScripts, name, prev.scripts[name], next.scripts[name])
+	}
+	return &diff, nil
+}
+
+func (d *PackageDiff) Equal() bool {
+	return len(d.Changes) == 0
+}
+
+func (d *PackageDiff) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(d)
+}
+
+func (d *PackageDiff) WriteReport(w io.Writer) error {
+	if d.Equal() {
+		return nil
+	}
+	fmt.Fprintf(w, "--- %s", d.Old)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "+++ %s", d.New)
+	fmt.Fprintln(w)
+	var section string
+	for _, c := range d.Changes {
+		if c.Section == DiffScripts {
+			fmt.Fprintf(w, "@@ %s: %s @@", c.Section, c.Name)
+			fmt.Fprintln(w)
+			writeLineDiff(w, c.Old, c.New)
+			section = ""
+			continue
+		}
+		if c.Section != section {
+			section = c.Section
+			fmt.Fprintf(w, "@@ %s @@", section)
+			fmt.Fprintln(w)
+		}
+		prefix := c.Name + " "
+		if c.Section == DiffMetadata {
+			prefix = c.Name + ": "
+		} else if c.Section == DiffDepends || c.Section == DiffConffiles {
+			prefix = ""
+		}
+		if c.Old != "" {
+			fmt.Fprintf(w, "-%s%s", prefix, c.Old)
+			fmt.Fprintln(w)
+		}
+		if c.New != "" {
+			fmt.Fprintf(w, "+%s%s", prefix, c.New)
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
+
+func (d *PackageDiff) compare(section, name, old, new string) {
+	if old == new {
+		return
+	}
+	d.Changes = append(d.Changes, DiffEntry{
+		Section: section,
+		Name:    name,
+		Old:     old,
+		New:     new,
+	})
+}
+
+func (d *PackageDiff) compareSets(section string, old, new []string) {
+	for _, str := range old {
+		if !slices.Contains(new, str) {
+			d.compare(section, str, str, "")
+		}
+	}
+	for _, str := range new {
+		if !slices.Contains(old, str) {
+			d.compare(section, str, "", str)
+		}
+	}
+}
+
+type diffState struct {
+	kind      string
+	pkg       *packfile.Package
+	depends   []string
+	files     map[string]string
+	conffiles []string
+	scripts   map[string]string
+}
+
+func loadDiffState(file string) (*diffState, error) {
+	payload, err := getPayload(file)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := Load(file)
+	if err != nil {
+		return nil, err
+	}
+	state := diffState{
+		kind:  packfile.Deb,
+		pkg:   pkg,
+		files: make(map[string]string),
+		scripts: map[string]string{
+			"preinst":  pkg.PreInst,
+			"postinst": pkg.PostInst,
+			"prerm":    pkg.PreRem,
+			"postrm":   pkg.PostRem,
+			"check":    pkg.CheckScript,
+		},
+	}
+	if getExtension(file) == ".rpm" {
+		state.kind = packfile.Rpm
+	}
+	for _, d := range pkg.Depends {
+		if d.Type == "provides" && d.Package == pkg.Name {
+			continue
+		}
+		if strings.HasPrefix(d.Package, "/") || strings.HasPrefix(d.Package, "rpmlib(") {
+			continue
+		}
+		state.depends = append(state.depends, formatDiffDependency(d))
+	}
+	slices.Sort(state.depends)
+	for _, r := range pkg.Files {
+		if r.IsConfig() {
+			state.conffiles = append(state.conffiles, r.Target)
+		}
+	}
+	slices.Sort(state.conffiles)
+
+	err = payload(file, func(h *tar.Header, r io.Reader) error {
+		name := strings.TrimLeft(strings.TrimPrefix(h.Name, "./"), "/")
+		name = strings.TrimSuffix(name, "/")
+		if name == "" || name == "." {
+			return nil
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			state.files[name] = fmt.Sprintf("dir mode=%04o", h.Mode&0o7777)
+		case tar.TypeSymlink:
+			state.files[name] = fmt.Sprintf("link target=%s", h.Linkname)
+		case tar.TypeReg:
+			sum := sha256.New()
+			size, err := io.Copy(sum, r)
+			if err != nil {
+				return err
+			}
+			state.files[name] = fmt.Sprintf("file mode=%04o size=%d sha256=%s", h.Mode&0o7777, size, hex.EncodeToString(sum.Sum(nil)))
+		default:
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+var diffFields = []struct {
+	name   string
+	common bool
+	get    func(*diffState) string
+}{
+	{
+		name:   "name",
+		common: true,
+		get:    func(s *diffState) string { return s.pkg.Name },
+	},
+	{
+		name:   "version",
+		common: true,
+		get: func(s *diffState) string {
+			return getInstallVersion(s.kind, s.pkg)
+		},
+	},
+	{
+		name:   "arch",
+		common: true,
+		get: func(s *diffState) string {
+			arch := s.pkg.Arch
+			if s.kind == packfile.Rpm {
+				arch = convertArch(arch, 1, 0)
+			}
+			if arch == packfile.ArchNo {
+				arch = packfile.ArchAll
+			}
+			return arch
+		},
+	},
+	{
+		name:   "summary",
+		common: true,
+		get:    func(s *diffState) string { return strings.TrimSpace(s.pkg.Summary) },
+	},
+	{
+		name:   "description",
+		common: true,
+		get:    func(s *diffState) string { return strings.TrimSpace(s.pkg.Desc) },
+	},
+	{
+		name:   "maintainer",
+		common: true,
+		get:    func(s *diffState) string { return s.pkg.Maintainer.String() },
+	},
+	{
+		name:   "section",
+		common: true,
+		get:    func(s *diffState) string { return s.pkg.Section },
+	},
+	{
+		name:   "homepage",
+		common: true,
+		get:    func(s *diffState) string { return s.pkg.Home },
+	},
+	{
+		name: "priority",
+		get:  func(s *diffState) string { return s.pkg.Priority },
+	},
+	{
+		name: "essential",
+		get: func(s *diffState) string {
+			if s.pkg.Essential {
+				return "yes"
+			}
+			return ""
+		},
+	},
+	{
+		name: "license",
+		get:  func(s *diffState) string { return s.pkg.License },
+	},
+	{
+		name: "vendor",
+		get:  func(s *diffState) string { return s.pkg.Vendor },
+	},
+	{
+		name: "distribution",
+		get:  func(s *diffState) string { return s.pkg.Distrib },
+	},
+	{
+		name: "os",
+		get:  func(s *diffState) string { return s.pkg.Os },
+	},
+}
+
+func formatDiffDependency(dep packfile.Dependency) string {
+	var str strings.Builder
+	str.WriteString(dep.Type)
+	str.WriteString(": ")
+	str.WriteString(dep.Package)
+	if dep.Arch != "" {
+		str.WriteString(":")
+		str.WriteString(dep.Arch)
+	}
+	if dep.Version != "" {
+		op := "="
+		switch dep.Constraint {
+		case packfile.ConstraintNe:
+			op = "!="
+		case packfile.ConstraintGt:
+			op = ">"
+		case packfile.ConstraintGe, "":
+			op = ">="
+		case packfile.ConstraintLt:
+			op = "<"
+		case packfile.ConstraintLe:
+			op = "<="
+		default:
+		}
+		str.WriteString(" ")
+		str.WriteString(op)
+		str.WriteString(" ")
+		str.WriteString(dep.Version)
+	}
+	for _, alt := range dep.Alternatives {
+		str.WriteString(" | ")
+		str.WriteString(strings.TrimPrefix(formatDiffDependency(alt), alt.Type+": "))
+	}
+	return str.String()
+}
+
+func mergeKeys(a, b map[string]string) []string {
+	list := slices.Collect(maps.Keys(a))
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			list = append(list, k)
+		}
+	}
+	slices.Sort(list)
+	return list
+}
+
+func writeLineDiff(w io.Writer, old, new string) {
+	var (
+		prev = splitLines(old)
+		next = splitLines(new)
+		lcs  = make([][]int, len(prev)+1)
+	)
+	for i := range lcs {
+		lcs[i] = make([]int, len(next)+1)
+	}
+	for i := len(prev) - 1; i >= 0; i-- {
+		for j := len(next) - 1; j >= 0; j-- {
+			if prev[i] == next[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var i, j int
+	for i < len(prev) || j < len(next) {
+		switch {
+		case i < len(prev) && j < len(next) && prev[i] == next[j]:
+			fmt.Fprintf(w, " %s", prev[i])
+			i++
+			j++
+		case i < len(prev) && (j == len(next) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(w, "-%s", prev[i])
+			i++
+		default:
+			fmt.Fprintf(w, "+%s", next[j])
+			j++
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func splitLines(str string) []string {
+	if str == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(str, "\n"), "\n")
+}
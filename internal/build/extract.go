@@ -0,0 +1,169 @@
+package build
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/midbel/packit/internal/deb"
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/rpm"
+)
+
+const (
+	debControlDir = "DEBIAN"
+	rpmControlDir = "RPM"
+	rpmHeaderFile = "header"
+)
+
+type payloadFunc func(string, func(*tar.Header, io.Reader) error) error
+
+func getPayload(file string) (payloadFunc, error) {
+	switch ext := getExtension(file); ext {
+	case ".deb", ".ipk":
+		return deb.Payload, nil
+	case ".rpm":
+		return rpm.Payload, nil
+	default:
+		return nil, fmt.Errorf("%s: package type not supported", ext)
+	}
+}
+
+type Extractor struct {
+	Dir     string
+	Control bool
+}
+
+func (e *Extractor) Extract(file string) error {
+	if e.Dir == "" {
+		return fmt.Errorf("no output directory given")
+	}
+	payload, err := getPayload(file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(e.Dir, 0o755); err != nil {
+		return err
+	}
+	if err := e.extractPayload(file, payload); err != nil {
+		return err
+	}
+	if !e.Control {
+		return nil
+	}
+	if getExtension(file) == ".rpm" {
+		return e.extractRpmControl(file)
+	}
+	return e.extractDebControl(file)
+}
+
+func (e *Extractor) extractPayload(file string, payload payloadFunc) error {
+	dirs := make(map[string]time.Time)
+	err := payload(file, func(h *tar.Header, r io.Reader) error {
+		name, target, err := securePath(e.Dir, h.Name)
+		if err != nil || name == "" {
+			return err
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, os.FileMode(h.Mode&0o7777)|0o700); err != nil {
+				return err
+			}
+			dirs[target] = h.ModTime
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			return os.Symlink(h.Linkname, target)
+		case tar.TypeReg:
+			return writeInstallFile(target, h, r)
+		default:
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	list := slices.Collect(maps.Keys(dirs))
+	slices.Sort(list)
+	slices.Reverse(list)
+	for _, d := range list {
+		if when := dirs[d]; !when.IsZero() {
+			if err := os.Chtimes(d, when, when); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Extractor) extractDebControl(file string) error {
+	dir := filepath.Join(e.Dir, debControlDir)
+	return deb.ControlMembers(file, func(h *tar.Header, r io.Reader) error {
+		if h.Typeflag != tar.TypeReg {
+			return nil
+		}
+		name, target, err := securePath(dir, h.Name)
+		if err != nil || name == "" {
+			return err
+		}
+		return writeInstallFile(target, h, r)
+	})
+}
+
+func (e *Extractor) extractRpmControl(file string) error {
+	info, err := rpm.Info(file)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(e.Dir, rpmControlDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	scripts := []struct {
+		Name   string
+		Script string
+	}{
+		{Name: "pre", Script: info.PreInst},
+		{Name: "post", Script: info.PostInst},
+		{Name: "preun", Script: info.PreRem},
+		{Name: "postun", Script: info.PostRem},
+		{Name: "verifyscript", Script: info.CheckScript},
+	}
+	for _, s := range scripts {
+		if strings.TrimSpace(s.Script) == "" {
+			continue
+		}
+		h := tar.Header{
+			Mode:    packfile.PermExec,
+			ModTime: info.BuildTime,
+		}
+		if err := writeInstallFile(filepath.Join(dir, s.Name), &h, strings.NewReader(s.Script)); err != nil {
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	if err := rpm.DumpHeader(file, &buf); err != nil {
+		return err
+	}
+	h := tar.Header{
+		Mode:    packfile.PermFile,
+		ModTime: info.BuildTime,
+	}
+	return writeInstallFile(filepath.Join(dir, rpmHeaderFile), &h, &buf)
+}
@@ -0,0 +1,171 @@
+package build
+
+import (
+	"archive/tar"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/midbel/packit/internal/deb"
+	"github.com/midbel/packit/internal/packfile"
+)
+
+const (
+	packfileName = "Packfile"
+	payloadDir   = "files"
+)
+
+//go:embed templates/packfile.tpl
+var packfileTemplate string
+
+//go:embed templates/scaffold.tpl
+var scaffoldTemplate string
+
+type packfileResource struct {
+	Source  string
+	Target  string
+	Perm    int64
+	Conf    bool
+	Doc     bool
+	License bool
+	Readme  bool
+}
+
+type packfileContext struct {
+	*packfile.Package
+	Source string
+	Files  []packfileResource
+	Links  []string
+}
+
+func InitFromPackage(file, context string) error {
+	if context == "" {
+		context = "."
+	}
+	target, err := getPackfileTarget(context)
+	if err != nil {
+		return err
+	}
+	payload, err := getPayload(file)
+	if err != nil {
+		return err
+	}
+	pkg, err := Load(file)
+	if err != nil {
+		return err
+	}
+	ctx := packfileContext{
+		Package: pkg,
+		Source:  filepath.Base(file),
+	}
+	var changelog string
+	if getExtension(file) != ".rpm" {
+		if pkg.Changes, changelog, err = deb.Changelog(file); err != nil {
+			return err
+		}
+	}
+	pkg.Depends = slices.DeleteFunc(pkg.Depends, func(d packfile.Dependency) bool {
+		return strings.HasPrefix(d.Package, "rpmlib(") || (d.Type == "provides" && d.Package == pkg.Name)
+	})
+	for i := range pkg.Depends {
+		if pkg.Depends[i].Type == "pre-depends" {
+			pkg.Depends[i].Type = "depends"
+		}
+	}
+	slices.SortFunc(pkg.Files, func(a, b packfile.Resource) int {
+		return strings.Compare(a.Target, b.Target)
+	})
+	for _, r := range pkg.Files {
+		if changelog != "" && r.Target == changelog {
+			continue
+		}
+		ctx.Files = append(ctx.Files, packfileResource{
+			Source:  path.Join(payloadDir, r.Target),
+			Target:  r.Target,
+			Perm:    r.Perm,
+			Conf:    r.IsConfig(),
+			Doc:     r.Flags&packfile.FileFlagDoc != 0,
+			License: r.Flags&packfile.FileFlagLicense != 0,
+			Readme:  r.Flags&packfile.FileFlagReadme != 0,
+		})
+	}
+	err = payload(file, func(h *tar.Header, _ io.Reader) error {
+		if h.Typeflag == tar.TypeSymlink {
+			name := strings.TrimLeft(strings.TrimPrefix(h.Name, "./"), "/")
+			ctx.Links = append(ctx.Links, name+" -> "+h.Linkname)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ext := Extractor{
+		Dir: filepath.Join(context, payloadDir),
+	}
+	if err := ext.Extract(file); err != nil {
+		return err
+	}
+	return writePackfile(target, packfileTemplate, ctx)
+}
+
+func getPackfileTarget(context string) (string, error) {
+	target := filepath.Join(context, packfileName)
+	if _, err := os.Stat(target); err == nil {
+		return "", fmt.Errorf("%s: file already exists", target)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	return target, nil
+}
+
+func writePackfile(file, text string, ctx any) error {
+	fn := template.FuncMap{
+		"quote":   quotePackfileString,
+		"heredoc": heredocPackfileString,
+	}
+	tpl, err := template.New("packfile").Funcs(fn).Parse(text)
+	if err != nil {
+		return err
+	}
+	w, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := tpl.Execute(w, ctx); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func quotePackfileString(str string) string {
+	switch {
+	case strings.Contains(str, "\n"):
+		return heredocPackfileString("TEXT", str)
+	case !strings.Contains(str, "'"):
+		return "'" + str + "'"
+	case !strings.Contains(str, "\""):
+		return "\"" + str + "\""
+	default:
+		return heredocPackfileString("TEXT", str)
+	}
+}
+
+func heredocPackfileString(delim, str string) string {
+	if !strings.HasSuffix(str, "\n") {
+		str += "\n"
+	}
+	lines := strings.Split(str, "\n")
+	for slices.Contains(lines, delim) {
+		delim += "X"
+	}
+	return "<<" + delim + "\n" + str + delim
+}
@@ -0,0 +1,510 @@
+package build
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/midbel/packit/internal/deb"
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/rpm"
+)
+
+const (
+	EnvRoot      = "PACKIT_ROOT"
+	installDbDir = "var/lib/packit"
+	maxSymlinks  = 40
+)
+
+type installedPackage struct {
+	Name      string            `json:"name"`
+	Version   string            `json:"version"`
+	Type      string            `json:"type"`
+	Files     []string          `json:"files"`
+	Dirs      []string          `json:"dirs"`
+	Conffiles map[string]string `json:"conffiles,omitempty"`
+	PreRem    string            `json:"prerm,omitempty"`
+	PostRem   string            `json:"postrm,omitempty"`
+}
+
+func (p *installedPackage) owns(file string) bool {
+	return slices.Contains(p.Files, file)
+}
+
+type Installer struct {
+	Root      string
+	NoScripts bool
+	Purge     bool
+}
+
+func (i *Installer) Install(file string) error {
+	if i.Root == "" {
+		return fmt.Errorf("no root directory given")
+	}
+	var (
+		pkg     *packfile.Package
+		kind    string
+		payload func(string, func(*tar.Header, io.Reader) error) error
+		err     error
+	)
+	switch ext := getExtension(file); ext {
+	case ".deb", ".ipk":
+		kind, payload = packfile.Deb, deb.Payload
+		pkg, err = deb.Load(file)
+	case ".rpm":
+		kind, payload = packfile.Rpm, rpm.Payload
+		var info *rpm.PackageInfo
+		if info, err = rpm.Info(file); err == nil {
+			pkg = &info.Package
+		}
+	default:
+		return fmt.Errorf("%s: package type not supported", ext)
+	}
+	if err != nil {
+		return err
+	}
+	old, err := i.readInstalled(pkg.Name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	curr := installedPackage{
+		Name:      pkg.Name,
+		Version:   getInstallVersion(kind, pkg),
+		Type:      kind,
+		Conffiles: make(map[string]string),
+		PreRem:    pkg.PreRem,
+		PostRem:   pkg.PostRem,
+	}
+	if old != nil && old.Type != kind {
+		return fmt.Errorf("%s: package already installed from a %s package", pkg.Name, old.Type)
+	}
+	if err := i.runPreInstall(&curr, old, pkg.PreInst); err != nil {
+		return err
+	}
+	conffiles := make(map[string]int64)
+	for _, r := range pkg.Files {
+		if r.IsConfig() {
+			conffiles[r.Target] = r.Flags
+		}
+	}
+	err = payload(file, func(h *tar.Header, r io.Reader) error {
+		name, target, err := resolvePath(i.Root, h.Name)
+		if err != nil || name == "" {
+			return err
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+				return nil
+			}
+			if err := os.MkdirAll(target, os.FileMode(h.Mode&0o7777)|0o700); err != nil {
+				return err
+			}
+			curr.Dirs = append(curr.Dirs, name)
+		case tar.TypeSymlink:
+			if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(h.Linkname, target); err != nil {
+				return err
+			}
+			curr.Files = append(curr.Files, name)
+		case tar.TypeReg:
+			flags, ok := conffiles[name]
+			if !ok {
+				if err := writeInstallFile(target, h, r); err != nil {
+					return err
+				}
+				curr.Files = append(curr.Files, name)
+				break
+			}
+			var buf bytes.Buffer
+			if _, err := io.Copy(&buf, r); err != nil {
+				return err
+			}
+			sum := md5.Sum(buf.Bytes())
+			curr.Conffiles[name] = hex.EncodeToString(sum[:])
+			dest, err := i.resolveConffile(kind, name, target, flags, curr.Conffiles[name], old)
+			if err != nil {
+				return err
+			}
+			if err := writeInstallFile(dest, h, &buf); err != nil {
+				return err
+			}
+			curr.Files = append(curr.Files, name)
+			if suffix := strings.TrimPrefix(dest, target); suffix != "" {
+				curr.Files = append(curr.Files, name+suffix)
+			}
+			target = dest
+		default:
+			return nil
+		}
+		return chownInstallFile(target, h)
+	})
+	if err != nil {
+		return err
+	}
+	if old != nil && kind != packfile.Rpm {
+		if err := i.removeFiles(old, &curr); err != nil {
+			return err
+		}
+	}
+	if err := i.writeInstalled(&curr); err != nil {
+		return err
+	}
+	return i.runPostInstall(&curr, old, pkg.PostInst)
+}
+
+func (i *Installer) Remove(name string) error {
+	if i.Root == "" {
+		return fmt.Errorf("no root directory given")
+	}
+	pkg, err := i.readInstalled(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s: package not installed", name)
+		}
+		return err
+	}
+	preArg, postArg := "remove", "remove"
+	if pkg.Type == packfile.Rpm {
+		preArg, postArg = "0", "0"
+	} else if i.Purge {
+		postArg = "purge"
+	}
+	if err := i.runScript(pkg, "prerm", pkg.PreRem, preArg); err != nil {
+		return err
+	}
+	if err := i.removeFiles(pkg, nil); err != nil {
+		return err
+	}
+	if err := i.runScript(pkg, "postrm", pkg.PostRem, postArg); err != nil {
+		return err
+	}
+	return os.Remove(i.installedFile(name))
+}
+
+func (i *Installer) runPreInstall(curr, old *installedPackage, script string) error {
+	if curr.Type == packfile.Rpm {
+		arg := "1"
+		if old != nil {
+			arg = "2"
+		}
+		return i.runScript(curr, "preinst", script, arg)
+	}
+	if old == nil {
+		return i.runScript(curr, "preinst", script, "install")
+	}
+	if err := i.runScript(old, "prerm", old.PreRem, "upgrade", curr.Version); err != nil {
+		return err
+	}
+	return i.runScript(curr, "preinst", script, "upgrade", old.Version)
+}
+
+func (i *Installer) runPostInstall(curr, old *installedPackage, script string) error {
+	if curr.Type == packfile.Rpm {
+		if old == nil {
+			return i.runScript(curr, "postinst", script, "1")
+		}
+		if err := i.runScript(curr, "postinst", script, "2"); err != nil {
+			return err
+		}
+		if err := i.runScript(old, "prerm", old.PreRem, "1"); err != nil {
+			return err
+		}
+		if err := i.removeFiles(old, curr); err != nil {
+			return err
+		}
+		return i.runScript(old, "postrm", old.PostRem, "1")
+	}
+	if old == nil {
+		return i.runScript(curr, "postinst", script, "configure")
+	}
+	if err := i.runScript(old, "postrm", old.PostRem, "upgrade", curr.Version); err != nil {
+		return err
+	}
+	return i.runScript(curr, "postinst", script, "configure", old.Version)
+}
+
+func (i *Installer) runScript(pkg *installedPackage, name, script string, args ...string) error {
+	if i.NoScripts || strings.TrimSpace(script) == "" {
+		return nil
+	}
+	root, err := filepath.Abs(i.Root)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("sh", append([]string{"-c", script, name}, args...)...)
+	cmd.Dir = root
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), EnvRoot+"="+root)
+	if pkg.Type == packfile.Rpm {
+		cmd.Env = append(cmd.Env, "RPM_PACKAGE_NAME="+pkg.Name, "RPM_PACKAGE_VERSION="+pkg.Version)
+	} else {
+		cmd.Env = append(cmd.Env, "DPKG_ROOT="+root, "DPKG_MAINTSCRIPT_PACKAGE="+pkg.Name, "DPKG_MAINTSCRIPT_NAME="+name)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %s script failed: %w", pkg.Name, name, err)
+	}
+	return nil
+}
+
+func (i *Installer) resolveConffile(kind, name, target string, flags int64, sum string, old *installedPackage) (string, error) {
+	curr, err := getFileChecksum(target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return target, nil
+		}
+		return "", err
+	}
+	if curr == sum {
+		return target, nil
+	}
+	if old != nil && old.Conffiles[name] == curr {
+		return target, nil
+	}
+	if kind != packfile.Rpm {
+		return target + ".dpkg-new", nil
+	}
+	if flags&packfile.FileFlagNoReplace != 0 {
+		return target + ".rpmnew", nil
+	}
+	return target, os.Rename(target, target+".rpmsave")
+}
+
+func (i *Installer) removeFiles(pkg, keep *installedPackage) error {
+	files := slices.Clone(pkg.Files)
+	slices.Sort(files)
+	slices.Reverse(files)
+	for _, name := range files {
+		if keep != nil && keep.owns(name) {
+			continue
+		}
+		_, target, err := resolvePath(i.Root, name)
+		if err != nil {
+			return err
+		}
+		if sum, ok := pkg.Conffiles[name]; ok {
+			if keep != nil && keep.Conffiles[name] != "" {
+				continue
+			}
+			curr, err := getFileChecksum(target)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if pkg.Type != packfile.Rpm && !i.Purge {
+				continue
+			}
+			if pkg.Type == packfile.Rpm && curr != sum {
+				if err := os.Rename(target, target+".rpmsave"); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	dirs := slices.Clone(pkg.Dirs)
+	slices.Sort(dirs)
+	slices.Reverse(dirs)
+	for _, name := range dirs {
+		if keep != nil && slices.Contains(keep.Dirs, name) {
+			continue
+		}
+		if _, target, err := resolvePath(i.Root, name); err == nil {
+			os.Remove(target)
+		}
+	}
+	return nil
+}
+
+func (i *Installer) installedFile(name string) string {
+	return filepath.Join(i.Root, installDbDir, name+".json")
+}
+
+func (i *Installer) readInstalled(name string) (*installedPackage, error) {
+	buf, err := os.ReadFile(i.installedFile(name))
+	if err != nil {
+		return nil, err
+	}
+	var pkg installedPackage
+	if err := json.Unmarshal(buf, &pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+func (i *Installer) writeInstalled(pkg *installedPackage) error {
+	file := i.installedFile(pkg.Name)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf, 0o644)
+}
+
+func writeInstallFile(target string, h *tar.Header, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	w, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(h.Mode&0o777))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, os.FileMode(h.Mode&0o7777)); err != nil {
+		return err
+	}
+	if h.ModTime.IsZero() {
+		return nil
+	}
+	return os.Chtimes(target, h.ModTime, h.ModTime)
+}
+
+func chownInstallFile(target string, h *tar.Header) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	return os.Lchown(target, h.Uid, h.Gid)
+}
+
+func securePath(root, name string) (string, string, error) {
+	file, err := cleanPath(name)
+	if err != nil || file == "" {
+		return "", "", err
+	}
+	dir := root
+	for _, p := range strings.Split(path.Dir(file), "/") {
+		if p == "." {
+			continue
+		}
+		dir = filepath.Join(dir, p)
+		i, err := os.Lstat(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+			return "", "", err
+		}
+		if i.Mode()&os.ModeSymlink != 0 {
+			return "", "", fmt.Errorf("%s: path goes through a symbolic link", name)
+		}
+	}
+	return file, filepath.Join(root, filepath.FromSlash(file)), nil
+}
+
+func resolvePath(root, name string) (string, string, error) {
+	file, err := cleanPath(name)
+	if err != nil || file == "" {
+		return "", "", err
+	}
+	var (
+		top   = filepath.Clean(root)
+		dir   = top
+		parts = strings.Split(path.Dir(file), "/")
+		links int
+	)
+	for len(parts) > 0 {
+		p := parts[0]
+		parts = parts[1:]
+		switch p {
+		case "", ".":
+			continue
+		case "..":
+			if dir == top {
+				return "", "", fmt.Errorf("%s: path goes outside of %s", name, root)
+			}
+			dir = filepath.Dir(dir)
+			continue
+		}
+		next := filepath.Join(dir, p)
+		i, err := os.Lstat(next)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				dir = filepath.Join(append([]string{next}, parts...)...)
+				break
+			}
+			return "", "", err
+		}
+		if i.Mode()&os.ModeSymlink == 0 {
+			dir = next
+			continue
+		}
+		if links++; links > maxSymlinks {
+			return "", "", fmt.Errorf("%s: too many levels of symbolic links", name)
+		}
+		link, err := os.Readlink(next)
+		if err != nil {
+			return "", "", err
+		}
+		link = filepath.ToSlash(link)
+		if path.IsAbs(link) {
+			dir = top
+		}
+		parts = append(strings.Split(link, "/"), parts...)
+	}
+	return file, filepath.Join(dir, path.Base(file)), nil
+}
+
+func cleanPath(name string) (string, error) {
+	file := strings.TrimLeft(strings.TrimPrefix(name, "./"), "/")
+	if file == "" || file == "." {
+		return "", nil
+	}
+	parts := strings.Split(file, "/")
+	if slices.Contains(parts, "..") {
+		return "", fmt.Errorf("%s: invalid path in package", name)
+	}
+	return path.Clean(file), nil
+}
+
+func getFileChecksum(file string) (string, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	sum := md5.New()
+	if _, err := io.Copy(sum, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+func getInstallVersion(kind string, pkg *packfile.Package) string {
+	if kind != packfile.Rpm || pkg.Release == "" {
+		return pkg.Version
+	}
+	return pkg.Version + "-" + pkg.Release
+}
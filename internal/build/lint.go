@@ -0,0 +1,495 @@
+package build
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+const maxSummaryLength = 80
+
+type LintIssue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Message  string `json:"message"`
+}
+
+func makeLintIssue(rule LintRule, where packfile.Location, msg string) LintIssue {
+	return LintIssue{
+		Rule:     rule.Name,
+		Severity: rule.Severity,
+		File:     where.File,
+		Line:     where.Line,
+		Column:   where.Column,
+		Message:  msg,
+	}
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", i.File, i.Line, i.Column, i.Severity, i.Message, i.Rule)
+}
+
+type LintRule struct {
+	Name     string
+	Severity string
+	Desc     string
+
+	check func(*lintState)
+}
+
+var lintRules = []LintRule{
+	{
+		Name:     "maintainer",
+		Severity: SeverityError,
+		Desc:     "package has a maintainer",
+		check:    lintMaintainer,
+	},
+	{
+		Name:     "summary",
+		Severity: SeverityError,
+		Desc:     "package has a summary",
+		check:    lintSummary,
+	},
+	{
+		Name:     "description",
+		Severity: SeverityWarning,
+		Desc:     "package has a description",
+		check:    lintDescription,
+	},
+	{
+		Name:     "summary-length",
+		Severity: SeverityWarning,
+		Desc:     "summary is not longer than 80 characters",
+		check:    lintSummaryLength,
+	},
+	{
+		Name:     "version",
+		Severity: SeverityError,
+		Desc:     "version and release are valid for the package format",
+		check:    lintVersion,
+	},
+	{
+		Name:     "dependency-type",
+		Severity: SeverityError,
+		Desc:     "dependency type is known",
+		check:    lintDependencyType,
+	},
+	{
+		Name:     "dependency-constraint",
+		Severity: SeverityError,
+		Desc:     "dependency version constraint is known",
+		check:    lintDependencyConstraint,
+	},
+	{
+		Name:     "fhs",
+		Severity: SeverityWarning,
+		Desc:     "file is installed in a directory of the FHS",
+		check:    lintFilesystem,
+	},
+	{
+		Name:     "duplicate-target",
+		Severity: SeverityError,
+		Desc:     "file target is used only once",
+		check:    lintDuplicateTarget,
+	},
+	{
+		Name:     "conffile",
+		Severity: SeverityWarning,
+		Desc:     "configuration file is installed under /etc",
+		check:    lintConffile,
+	},
+	{
+		Name:     "exec-permission",
+		Severity: SeverityWarning,
+		Desc:     "executable file has the exec permission",
+		check:    lintExecPermission,
+	},
+	{
+		Name:     "format-option",
+		Severity: SeverityWarning,
+		Desc:     "option is supported by the package format",
+		check:    lintFormatOption,
+	},
+}
+
+func LintRules() []LintRule {
+	return slices.Clone(lintRules)
+}
+
+type Linter struct {
+	Packfile   string
+	IgnoreFile string
+	Types      []string
+	Rules      []string
+	Skip       []string
+	Severities map[string]string
+}
+
+func (t Linter) Lint(context string) ([]LintIssue, error) {
+	if context == "" {
+		return nil, fmt.Errorf("no context given")
+	}
+	rules, err := t.getRules()
+	if err != nil {
+		return nil, err
+	}
+	cfg := packfile.DecoderConfig{
+		Packfile:   t.Packfile,
+		IgnoreFile: t.IgnoreFile,
+		NoIgnore:   t.IgnoreFile == "",
+	}
+	if len(t.Types) == 1 {
+		cfg.Type = t.Types[0]
+	}
+	d, err := packfile.NewDecoder(context, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := d.Decode()
+	defer closeResources(pkg)
+	if err != nil {
+		rule := LintRule{
+			Name:     "syntax",
+			Severity: SeverityError,
+		}
+		return []LintIssue{makeLintIssue(rule, d.Location(), err.Error())}, nil
+	}
+	state := lintState{
+		Package:   pkg,
+		locations: d.Locations(),
+		file:      t.Packfile,
+		kinds:     t.Types,
+		explicit:  len(t.Types) > 0,
+	}
+	if !state.explicit {
+		state.kinds = []string{packfile.Deb, packfile.Rpm}
+	}
+	for _, r := range rules {
+		state.rule = r
+		r.check(&state)
+	}
+	slices.SortStableFunc(state.issues, func(a, b LintIssue) int {
+		return cmp.Or(strings.Compare(a.File, b.File), a.Line-b.Line, a.Column-b.Column)
+	})
+	return state.issues, nil
+}
+
+func (t Linter) getRules() ([]LintRule, error) {
+	for _, n := range slices.Concat(t.Rules, t.Skip) {
+		if !slices.ContainsFunc(lintRules, func(r LintRule) bool { return r.Name == n }) {
+			return nil, fmt.Errorf("%s: unknown rule", n)
+		}
+	}
+	var list []LintRule
+	for _, r := range lintRules {
+		if len(t.Rules) > 0 && !slices.Contains(t.Rules, r.Name) {
+			continue
+		}
+		if slices.Contains(t.Skip, r.Name) {
+			continue
+		}
+		list = append(list, r)
+	}
+	for name, level := range t.Severities {
+		switch level {
+		case SeverityError, SeverityWarning, SeverityInfo:
+		default:
+			return nil, fmt.Errorf("%s: unknown severity", level)
+		}
+		ix := slices.IndexFunc(list, func(r LintRule) bool { return r.Name == name })
+		if ix < 0 && !slices.ContainsFunc(lintRules, func(r LintRule) bool { return r.Name == name }) {
+			return nil, fmt.Errorf("%s: unknown rule", name)
+		}
+		if ix >= 0 {
+			list[ix].Severity = level
+		}
+	}
+	return list, nil
+}
+
+func WriteLintIssues(issues []LintIssue, format string, w io.Writer) error {
+	if !IsTextOutput(format) {
+		if issues == nil {
+			issues = []LintIssue{}
+		}
+		return WriteOutput(w, format, issues)
+	}
+	for _, i := range issues {
+		fmt.Fprintln(w, i)
+	}
+	return nil
+}
+
+func HasLintErrors(issues []LintIssue) bool {
+	return slices.ContainsFunc(issues, func(i LintIssue) bool {
+		return i.Severity == SeverityError
+	})
+}
+
+type lintState struct {
+	*packfile.Package
+
+	locations *packfile.Locations
+
+	file     string
+	kinds    []string
+	explicit bool
+	rule     LintRule
+	issues   []LintIssue
+}
+
+func (s *lintState) report(where packfile.Location, msg string, args ...any) {
+	s.issues = append(s.issues, makeLintIssue(s.rule, where, fmt.Sprintf(msg, args...)))
+}
+
+func (s *lintState) where(options ...string) packfile.Location {
+	for _, o := range options {
+		if pos, ok := s.locations.Option(o); ok {
+			return pos
+		}
+	}
+	if pos, ok := s.locations.Option("package"); ok {
+		return pos
+	}
+	if pos, ok := s.locations.Option("name"); ok {
+		return pos
+	}
+	return packfile.Location{
+		File: s.file,
+		Position: packfile.Position{
+			Line:   1,
+			Column: 1,
+		},
+	}
+}
+
+func lintMaintainer(s *lintState) {
+	if s.Maintainer.Name == "" {
+		s.report(s.where("maintainer"), "package has no maintainer")
+	}
+}
+
+func lintSummary(s *lintState) {
+	if strings.TrimSpace(s.Summary) == "" {
+		s.report(s.where("summary"), "package has no summary")
+	}
+}
+
+func lintDescription(s *lintState) {
+	if strings.TrimSpace(s.Desc) == "" {
+		s.report(s.where("desc", "description"), "package has no description")
+	}
+}
+
+func lintSummaryLength(s *lintState) {
+	if n := utf8.RuneCountInString(s.Summary); n > maxSummaryLength {
+		s.report(s.where("summary"), "summary is %d characters long (max %d)", n, maxSummaryLength)
+	}
+}
+
+var (
+	debVersion    = regexp.MustCompile(`^([0-9]+:)?[0-9][A-Za-z0-9.+~-]*$`)
+	rpmVersion    = regexp.MustCompile(`^[A-Za-z0-9._+~^]+$`)
+	apkVersion    = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*[a-z]?(_(alpha|beta|pre|rc|cvs|svn|git|hg|p)[0-9]*)*$`)
+	pacmanVersion = regexp.MustCompile(`^([0-9]+:)?[^:/\-\s]+$`)
+	numRelease    = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+)
+
+func lintVersion(s *lintState) {
+	for _, k := range s.kinds {
+		var version, release *regexp.Regexp
+		switch k {
+		case packfile.Deb, packfile.Ipk:
+			version = debVersion
+		case packfile.Rpm:
+			version, release = rpmVersion, rpmVersion
+		case packfile.Apk:
+			version, release = apkVersion, numRelease
+		case packfile.Pacman:
+			version, release = pacmanVersion, numRelease
+		default:
+			continue
+		}
+		if !version.MatchString(s.Version) {
+			s.report(s.where("version"), "%s: invalid version %q", k, s.Version)
+		}
+		if release != nil && s.Release != "" && !release.MatchString(s.Release) {
+			s.report(s.where("release"), "%s: invalid release %q", k, s.Release)
+		}
+	}
+}
+
+func (s *lintState) dependencyLocation(i int) packfile.Location {
+	if i < len(s.locations.Depends) {
+		return s.locations.Depends[i]
+	}
+	return s.where("depends")
+}
+
+func (s *lintState) fileLocation(i int) packfile.Location {
+	if i < len(s.locations.Files) {
+		return s.locations.Files[i]
+	}
+	return s.where("file")
+}
+
+func lintDependencyType(s *lintState) {
+	for i, d := range s.Depends {
+		switch {
+		case d.Type == "":
+			s.report(s.dependencyLocation(i), "%s: dependency has no type and is ignored", d.Package)
+		case !slices.Contains(dependencyTypes, d.Type):
+			s.report(s.dependencyLocation(i), "%s: unknown dependency type %q", d.Package, d.Type)
+		}
+	}
+}
+
+func lintDependencyConstraint(s *lintState) {
+	constraints := []string{
+		packfile.ConstraintEq,
+		packfile.ConstraintNe,
+		packfile.ConstraintGt,
+		packfile.ConstraintGe,
+		packfile.ConstraintLt,
+		packfile.ConstraintLe,
+	}
+	for i, d := range s.Depends {
+		if d.Constraint == "" || slices.Contains(constraints, d.Constraint) {
+			continue
+		}
+		s.report(s.dependencyLocation(i), "%s: unknown version constraint %q", d.Package, d.Constraint)
+	}
+}
+
+var fhsDirs = map[string][]string{
+	"bin":   nil,
+	"boot":  nil,
+	"etc":   nil,
+	"lib":   nil,
+	"lib32": nil,
+	"lib64": nil,
+	"opt":   nil,
+	"sbin":  nil,
+	"srv":   nil,
+	"usr":   {"bin", "games", "include", "lib", "lib32", "lib64", "libexec", "local", "sbin", "share", "src"},
+	"var":   {"cache", "games", "lib", "local", "lock", "log", "mail", "opt", "run", "spool", "tmp"},
+}
+
+func lintFilesystem(s *lintState) {
+	for i, r := range s.Files {
+		var (
+			target    = cleanTarget(r.Target)
+			top, rest = cutTarget(target)
+			sub, _    = cutTarget(rest)
+		)
+		subs, ok := fhsDirs[top]
+		if ok && rest != "" && (subs == nil || slices.Contains(subs, sub)) {
+			continue
+		}
+		s.report(s.fileLocation(i), "%s: target is outside of the FHS", target)
+	}
+}
+
+func lintDuplicateTarget(s *lintState) {
+	seen := make(map[string]int)
+	for i, r := range s.Files {
+		target := cleanTarget(r.Target)
+		if j, ok := seen[target]; ok {
+			s.report(s.fileLocation(i), "%s: target already used at line %d", target, s.fileLocation(j).Line)
+			continue
+		}
+		seen[target] = i
+	}
+}
+
+func lintConffile(s *lintState) {
+	for i, r := range s.Files {
+		target := cleanTarget(r.Target)
+		if r.IsConfig() && !strings.HasPrefix(target, packfile.DirEtc+"/") {
+			s.report(s.fileLocation(i), "%s: configuration file outside of /etc", target)
+		}
+	}
+}
+
+var execDirs = []string{
+	"bin",
+	"sbin",
+	"usr/bin",
+	"usr/sbin",
+	"usr/games",
+	"usr/local/bin",
+	"usr/local/sbin",
+}
+
+func lintExecPermission(s *lintState) {
+	for i, r := range s.Files {
+		if r.Perm&0o111 != 0 {
+			continue
+		}
+		target := cleanTarget(r.Target)
+		if slices.Contains(execDirs, path.Dir(target)) {
+			s.report(s.fileLocation(i), "%s: file installed in %s without exec permission (%04o)", target, path.Dir(target), r.Perm)
+			continue
+		}
+		f, ok := r.Local.(*os.File)
+		if !ok {
+			continue
+		}
+		if fi, err := f.Stat(); err == nil && fi.Mode().Perm()&0o111 != 0 {
+			s.report(s.fileLocation(i), "%s: source file is executable but permission is %04o", target, r.Perm)
+		}
+	}
+}
+
+var formatOptions = map[string][]string{
+	packfile.Deb: {"release", "distrib"},
+	packfile.Ipk: {"release", "distrib"},
+	packfile.Rpm: {"priority", "shlibs"},
+}
+
+func lintFormatOption(s *lintState) {
+	if !s.explicit {
+		return
+	}
+	for _, k := range s.kinds {
+		for _, o := range formatOptions[k] {
+			if pos, ok := s.locations.Option(o); ok {
+				s.report(pos, "%s: option is ignored when building %s packages", o, k)
+			}
+		}
+		if k == packfile.Rpm {
+			continue
+		}
+		for i, r := range s.Files {
+			if r.Flags&packfile.FileFlagGhost != 0 {
+				s.report(s.fileLocation(i), "%s: ghost files are not supported by %s packages", cleanTarget(r.Target), k)
+			}
+		}
+	}
+}
+
+func cleanTarget(target string) string {
+	return strings.TrimPrefix(path.Clean("/"+target), "/")
+}
+
+func cutTarget(target string) (string, string) {
+	top, rest, _ := strings.Cut(target, "/")
+	return top, rest
+}
@@ -0,0 +1,444 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/tape"
+)
+
+const (
+	OutputText     = "text"
+	OutputJSON     = "json"
+	OutputYAML     = "yaml"
+	OutputTemplate = "template="
+)
+
+type MaintainerDocument struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type DependencyDocument struct {
+	Type         string               `json:"type"`
+	Package      string               `json:"package"`
+	Constraint   string               `json:"constraint"`
+	Version      string               `json:"version"`
+	Arch         string               `json:"arch"`
+	Alternatives []DependencyDocument `json:"alternatives"`
+}
+
+type ChangeDocument struct {
+	Version    string             `json:"version"`
+	When       string             `json:"when"`
+	Summary    string             `json:"summary"`
+	Changes    []string           `json:"changes"`
+	Maintainer MaintainerDocument `json:"maintainer"`
+}
+
+type ScriptsDocument struct {
+	PreInst     string `json:"preinst"`
+	PostInst    string `json:"postinst"`
+	PreRem      string `json:"prerm"`
+	PostRem     string `json:"postrm"`
+	CheckScript string `json:"check"`
+}
+
+type PackageDocument struct {
+	File         string               `json:"file"`
+	Type         string               `json:"type"`
+	Name         string               `json:"name"`
+	Version      string               `json:"version"`
+	Release      string               `json:"release"`
+	Epoch        int64                `json:"epoch,omitempty"`
+	Summary      string               `json:"summary"`
+	Description  string               `json:"description"`
+	Arch         string               `json:"arch"`
+	Os           string               `json:"os"`
+	Section      string               `json:"section"`
+	Priority     string               `json:"priority"`
+	License      string               `json:"license"`
+	Homepage     string               `json:"homepage"`
+	Vendor       string               `json:"vendor"`
+	Distribution string               `json:"distribution"`
+	Essential    bool                 `json:"essential"`
+	Compiler     string               `json:"compiler"`
+	Maintainer   MaintainerDocument   `json:"maintainer"`
+	Size         int64                `json:"size"`
+	ArchiveSize  int64                `json:"archive_size,omitempty"`
+	BuildTime    string               `json:"build_time"`
+	BuildHost    string               `json:"build_host,omitempty"`
+	Compressor   string               `json:"compressor,omitempty"`
+	SourceRpm    string               `json:"source_rpm,omitempty"`
+	Base         string               `json:"base,omitempty"`
+	Origin       string               `json:"origin,omitempty"`
+	DataHash     string               `json:"data_hash,omitempty"`
+	Signature    string               `json:"signature,omitempty"`
+	Scripts      ScriptsDocument      `json:"scripts"`
+	Depends      []DependencyDocument `json:"depends"`
+	Changes      []ChangeDocument     `json:"changes"`
+}
+
+type FileDocument struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Mode    string `json:"mode"`
+	Size    int64  `json:"size"`
+	User    string `json:"user"`
+	Group   string `json:"group"`
+	Uid     int64  `json:"uid"`
+	Gid     int64  `json:"gid"`
+	ModTime string `json:"mtime"`
+}
+
+type ResourceDocument struct {
+	Source   string   `json:"source"`
+	Target   string   `json:"target"`
+	Mode     string   `json:"mode"`
+	Size     int64    `json:"size"`
+	ModTime  string   `json:"mtime"`
+	Flags    []string `json:"flags"`
+	Compress bool     `json:"compress"`
+	Strip    bool     `json:"strip"`
+}
+
+type VerifyDocument struct {
+	File   string   `json:"file"`
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors"`
+}
+
+func IsTextOutput(format string) bool {
+	return format == "" || format == OutputText
+}
+
+func WriteOutput(w io.Writer, format string, doc any) error {
+	switch {
+	case format == OutputJSON:
+		e := json.NewEncoder(w)
+		e.SetEscapeHTML(false)
+		e.SetIndent("", "  ")
+		return e.Encode(doc)
+	case format == OutputYAML:
+		for _, line := range yamlLines(reflect.ValueOf(doc)) {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case strings.HasPrefix(format, OutputTemplate):
+		text := strings.TrimPrefix(format, OutputTemplate)
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		tpl, err := template.New("output").Parse(text)
+		if err != nil {
+			return err
+		}
+		return tpl.Execute(w, doc)
+	default:
+		return fmt.Errorf("%s: output format not supported", format)
+	}
+}
+
+func WriteResources(files []packfile.Resource, format string, w io.Writer) error {
+	if IsTextOutput(format) {
+		for _, r := range files {
+			fmt.Fprintln(w, r.Path)
+		}
+		return nil
+	}
+	list := []ResourceDocument{}
+	for _, r := range files {
+		list = append(list, makeResourceDocument(r))
+	}
+	return WriteOutput(w, format, list)
+}
+
+func WriteDependencies(deps []packfile.Dependency, format string, w io.Writer) error {
+	if IsTextOutput(format) {
+		printDependencies(deps, w)
+		return nil
+	}
+	return WriteOutput(w, format, makeDependencyDocuments(deps))
+}
+
+func makeMaintainerDocument(m packfile.Maintainer) MaintainerDocument {
+	return MaintainerDocument{
+		Name:  m.Name,
+		Email: m.Email,
+	}
+}
+
+func makePackageDocument(file, kind string, pkg *packfile.Package) PackageDocument {
+	doc := PackageDocument{
+		File:         file,
+		Type:         kind,
+		Name:         pkg.Name,
+		Version:      pkg.Version,
+		Release:      pkg.Release,
+		Summary:      pkg.Summary,
+		Description:  pkg.Desc,
+		Arch:         pkg.Arch,
+		Os:           pkg.Os,
+		Section:      pkg.Section,
+		Priority:     pkg.Priority,
+		License:      pkg.License,
+		Homepage:     pkg.Home,
+		Vendor:       pkg.Vendor,
+		Distribution: pkg.Distrib,
+		Essential:    pkg.Essential,
+		Compiler:     strings.TrimSpace(pkg.BuildWith.Name + " " + pkg.BuildWith.Version),
+		Maintainer:   makeMaintainerDocument(pkg.Maintainer),
+		Scripts: ScriptsDocument{
+			PreInst:     pkg.PreInst,
+			PostInst:    pkg.PostInst,
+			PreRem:      pkg.PreRem,
+			PostRem:     pkg.PostRem,
+			CheckScript: pkg.CheckScript,
+		},
+		Depends: makeDependencyDocuments(pkg.Depends),
+		Changes: []ChangeDocument{},
+	}
+	for _, c := range pkg.Changes {
+		changes := c.Changes
+		if changes == nil {
+			changes = []string{}
+		}
+		doc.Changes = append(doc.Changes, ChangeDocument{
+			Version:    c.Version,
+			When:       formatDocumentTime(c.When),
+			Summary:    c.Summary,
+			Changes:    changes,
+			Maintainer: makeMaintainerDocument(c.Maintainer),
+		})
+	}
+	return doc
+}
+
+func makeDependencyDocuments(deps []packfile.Dependency) []DependencyDocument {
+	list := []DependencyDocument{}
+	for _, d := range deps {
+		list = append(list, DependencyDocument{
+			Type:         d.Type,
+			Package:      d.Package,
+			Constraint:   d.Constraint,
+			Version:      d.Version,
+			Arch:         d.Arch,
+			Alternatives: makeDependencyDocuments(d.Alternatives),
+		})
+	}
+	return list
+}
+
+func makeFileDocument(h *tape.Header) FileDocument {
+	var (
+		mode = os.FileMode(h.Mode)
+		kind = "file"
+	)
+	switch {
+	case mode.IsDir() || h.Mode&0o170000 == 0o040000:
+		kind = "dir"
+	case mode&os.ModeSymlink != 0 || h.Mode&0o170000 == 0o120000:
+		kind = "link"
+	default:
+	}
+	return FileDocument{
+		Name:    h.Filename,
+		Type:    kind,
+		Mode:    fmt.Sprintf("%04o", h.Mode&0o7777),
+		Size:    h.Size,
+		User:    h.User(),
+		Group:   h.Group(),
+		Uid:     h.Uid,
+		Gid:     h.Gid,
+		ModTime: formatDocumentTime(h.ModTime),
+	}
+}
+
+var resourceFlags = []struct {
+	Flag int64
+	Name string
+}{
+	{Flag: packfile.FileFlagConf, Name: "conf"},
+	{Flag: packfile.FileFlagDoc, Name: "doc"},
+	{Flag: packfile.FileFlagAllowMissing, Name: "missingok"},
+	{Flag: packfile.FileFlagNoReplace, Name: "noreplace"},
+	{Flag: packfile.FileFlagGhost, Name: "ghost"},
+	{Flag: packfile.FileFlagLicense, Name: "license"},
+	{Flag: packfile.FileFlagReadme, Name: "readme"},
+	{Flag: packfile.FileFlagExec, Name: "exec"},
+	{Flag: packfile.FileFlagDir, Name: "dir"},
+}
+
+func makeResourceDocument(r packfile.Resource) ResourceDocument {
+	doc := ResourceDocument{
+		Source:   r.Path,
+		Target:   r.Target,
+		Mode:     fmt.Sprintf("%04o", r.Perm),
+		Size:     r.Size,
+		ModTime:  formatDocumentTime(r.Lastmod),
+		Flags:    []string{},
+		Compress: r.Compress,
+		Strip:    r.Strip,
+	}
+	for _, f := range resourceFlags {
+		if r.Flags&f.Flag != 0 {
+			doc.Flags = append(doc.Flags, f.Name)
+		}
+	}
+	return doc
+}
+
+func formatDocumentTime(when time.Time) string {
+	if when.IsZero() {
+		return ""
+	}
+	return when.UTC().Format(time.RFC3339)
+}
+
+func yamlLines(v reflect.Value) []string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return []string{"null"}
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		var lines []string
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if opts == "omitempty" && v.Field(i).IsZero() {
+				continue
+			}
+			lines = append(lines, yamlEntry(name, v.Field(i))...)
+		}
+		if len(lines) == 0 {
+			return []string{"{}"}
+		}
+		return lines
+	case reflect.Map:
+		if v.Len() == 0 {
+			return []string{"{}"}
+		}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+		})
+		var lines []string
+		for _, k := range keys {
+			lines = append(lines, yamlEntry(yamlString(fmt.Sprint(k)), v.MapIndex(k))...)
+		}
+		return lines
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return []string{"[]"}
+		}
+		var lines []string
+		for i := 0; i < v.Len(); i++ {
+			item := yamlLines(v.Index(i))
+			lines = append(lines, "- "+item[0])
+			lines = append(lines, indentLines(item[1:])...)
+		}
+		return lines
+	case reflect.String:
+		return yamlScalar(v.String())
+	case reflect.Bool:
+		return []string{strconv.FormatBool(v.Bool())}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(v.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(v.Uint(), 10)}
+	case reflect.Float32, reflect.Float64:
+		return []string{strconv.FormatFloat(v.Float(), 'g', -1, 64)}
+	default:
+		return []string{yamlString(fmt.Sprint(v.Interface()))}
+	}
+}
+
+func yamlEntry(name string, v reflect.Value) []string {
+	lines := yamlLines(v)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		if lines[0] == "{}" || lines[0] == "[]" {
+			return []string{name + ": " + lines[0]}
+		}
+		return append([]string{name + ":"}, indentLines(lines)...)
+	default:
+		return append([]string{name + ": " + lines[0]}, indentLines(lines[1:])...)
+	}
+}
+
+func yamlScalar(str string) []string {
+	if !strings.Contains(strings.TrimSuffix(str, "\n"), "\n") || strings.HasPrefix(str, " ") || strings.HasSuffix(str, "\n\n") {
+		return []string{yamlString(str)}
+	}
+	head := "|-"
+	if strings.HasSuffix(str, "\n") {
+		head = "|"
+	}
+	lines := strings.Split(strings.TrimSuffix(str, "\n"), "\n")
+	return append([]string{head}, lines...)
+}
+
+func yamlString(str string) string {
+	switch strings.ToLower(str) {
+	case "", "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(str)
+	default:
+	}
+	if _, err := strconv.ParseFloat(str, 64); err == nil {
+		return strconv.Quote(str)
+	}
+	if strings.HasSuffix(str, " ") || strings.Contains(str, ": ") || strings.Contains(str, " #") {
+		return strconv.Quote(str)
+	}
+	for i, c := range str {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '/' || c == '.' {
+			continue
+		}
+		if i > 0 && (c >= '0' && c <= '9' || c == '-' || c == '+' || c == '@' || c == ' ') {
+			continue
+		}
+		return strconv.Quote(str)
+	}
+	return str
+}
+
+func indentLines(lines []string) []string {
+	list := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line != "" {
+			line = "  " + line
+		}
+		list = append(list, line)
+	}
+	return list
+}
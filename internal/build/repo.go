@@ -0,0 +1,55 @@
+package build
+
+import (
+	"fmt"
+
+	"github.com/midbel/packit/internal/apk"
+	"github.com/midbel/packit/internal/deb"
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/pgp"
+	"github.com/midbel/packit/internal/rpm"
+)
+
+type RepoBuilder struct {
+	Suite     string
+	Component string
+	Origin    string
+	Label     string
+	SignKey   string
+}
+
+func (b *RepoBuilder) Build(kind, dir string) error {
+	if dir == "" {
+		return fmt.Errorf("no directory given")
+	}
+	var (
+		key *pgp.Key
+		err error
+	)
+	if b.SignKey != "" {
+		if kind == packfile.Apk {
+			return fmt.Errorf("%s: repository is signed with the key given in %s", kind, apk.EnvPrivateKey)
+		}
+		if key, err = pgp.ReadPrivateKey(b.SignKey); err != nil {
+			return err
+		}
+	}
+	when := packfile.BuildTime()
+	switch kind {
+	case packfile.Deb:
+		config := deb.RepoConfig{
+			Suite:     b.Suite,
+			Component: b.Component,
+			Origin:    b.Origin,
+			Label:     b.Label,
+			Key:       key,
+		}
+		return deb.WriteRepository(dir, config, when)
+	case packfile.Rpm:
+		return rpm.WriteRepository(dir, key, when)
+	case packfile.Apk:
+		return apk.WriteRepository(dir, when)
+	default:
+		return fmt.Errorf("%s: repository type not supported", kind)
+	}
+}
@@ -0,0 +1,544 @@
+package build
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/midbel/packit/internal/git"
+	"github.com/midbel/packit/internal/packfile"
+)
+
+type projectManifest struct {
+	Name       string
+	Version    string
+	Summary    string
+	Home       string
+	License    string
+	Maintainer packfile.Maintainer
+	Compiler   packfile.Compiler
+}
+
+func (m *projectManifest) merge(other projectManifest) {
+	if m.Name == "" {
+		m.Name = other.Name
+	}
+	if m.Version == "" {
+		m.Version = other.Version
+	}
+	if m.Summary == "" {
+		m.Summary = other.Summary
+	}
+	if m.Home == "" {
+		m.Home = other.Home
+	}
+	if m.License == "" {
+		m.License = other.License
+	}
+	if m.Maintainer.Name == "" {
+		m.Maintainer = other.Maintainer
+	}
+	if m.Compiler.Name == "" {
+		m.Compiler = other.Compiler
+	}
+}
+
+type scaffoldContext struct {
+	projectManifest
+	LicenseTemplate string
+	Files           []packfileResource
+}
+
+var manPage = regexp.MustCompile(`\.([1-9])$`)
+
+func InitProject(context string) error {
+	if context == "" {
+		context = "."
+	}
+	target, err := getPackfileTarget(context)
+	if err != nil {
+		return err
+	}
+	var ctx scaffoldContext
+	if err := readProjectManifests(context, &ctx.projectManifest); err != nil {
+		return err
+	}
+	if ctx.Name == "" {
+		abs, err := filepath.Abs(context)
+		if err != nil {
+			return err
+		}
+		ctx.Name = filepath.Base(abs)
+	}
+	if ctx.Summary == "" {
+		ctx.Summary = readProjectTitle(context)
+	}
+	if ctx.Summary == "" {
+		ctx.Summary = ctx.Name
+	}
+	if ctx.Version == "" || ctx.Maintainer.Name == "" {
+		git.Load()
+	}
+	if ctx.Version == "" {
+		ctx.Version = strings.TrimPrefix(git.CurrentTag(), "v")
+	}
+	if ctx.Version == "" {
+		ctx.Version = packfile.DefaultVersion
+	}
+	if ctx.Maintainer.Name == "" {
+		ctx.Maintainer.Name = git.User()
+		ctx.Maintainer.Email = git.Email()
+	}
+	if err := scanProjectDocs(context, &ctx); err != nil {
+		return err
+	}
+	if err := scanProjectManPages(context, &ctx); err != nil {
+		return err
+	}
+	if err := scanProjectBin(context, &ctx); err != nil {
+		return err
+	}
+	if err := scanProjectEtc(context, &ctx); err != nil {
+		return err
+	}
+	return writePackfile(target, scaffoldTemplate, ctx)
+}
+
+func scanProjectDocs(context string, ctx *scaffoldContext) error {
+	es, err := os.ReadDir(context)
+	if err != nil {
+		return err
+	}
+	for _, e := range es {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		name := strings.ToUpper(e.Name())
+		switch {
+		case strings.HasPrefix(name, "README"):
+			ctx.Files = append(ctx.Files, packfileResource{
+				Source: e.Name(),
+				Target: path.Join("$docdir/$package", e.Name()),
+				Readme: true,
+			})
+		case strings.HasPrefix(name, "LICENSE"), strings.HasPrefix(name, "LICENCE"), strings.HasPrefix(name, "COPYING"):
+			if ctx.LicenseTemplate != "" {
+				continue
+			}
+			buf, err := os.ReadFile(filepath.Join(context, e.Name()))
+			if err != nil {
+				return err
+			}
+			if tpl, ok := packfile.GuessLicense(string(buf)); ok {
+				ctx.LicenseTemplate = tpl
+				ctx.Files = deleteLicenseFiles(ctx.Files)
+				continue
+			}
+			if hasLicenseFile(ctx.Files) {
+				continue
+			}
+			ctx.Files = append(ctx.Files, packfileResource{
+				Source:  e.Name(),
+				Target:  "$docdir/$package/copyright",
+				License: true,
+			})
+		}
+	}
+	return nil
+}
+
+func readProjectTitle(context string) string {
+	es, err := os.ReadDir(context)
+	if err != nil {
+		return ""
+	}
+	for _, e := range es {
+		if !e.Type().IsRegular() || !strings.HasPrefix(strings.ToUpper(e.Name()), "README") {
+			continue
+		}
+		buf, err := os.ReadFile(filepath.Join(context, e.Name()))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(buf), "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			if line == "" || strings.Trim(line, "=-*") == "" {
+				continue
+			}
+			if utf8.RuneCountInString(line) > maxSummaryLength {
+				break
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+func hasLicenseFile(files []packfileResource) bool {
+	for _, f := range files {
+		if f.License {
+			return true
+		}
+	}
+	return false
+}
+
+func deleteLicenseFiles(files []packfileResource) []packfileResource {
+	var list []packfileResource
+	for _, f := range files {
+		if !f.License {
+			list = append(list, f)
+		}
+	}
+	return list
+}
+
+func scanProjectManPages(context string, ctx *scaffoldContext) error {
+	for _, dir := range []string{"man", "doc/man", "docs/man"} {
+		err := walkProjectDir(context, dir, func(rel string) {
+			m := manPage.FindStringSubmatch(rel)
+			if m == nil {
+				return
+			}
+			ctx.Files = append(ctx.Files, packfileResource{
+				Source: rel,
+				Target: path.Join("usr/share/man", "man"+m[1], path.Base(rel)),
+				Doc:    true,
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanProjectBin(context string, ctx *scaffoldContext) error {
+	es, err := os.ReadDir(filepath.Join(context, "bin"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range es {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		i, err := e.Info()
+		if err != nil {
+			return err
+		}
+		if i.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		ctx.Files = append(ctx.Files, packfileResource{
+			Source: path.Join("bin", e.Name()),
+			Target: path.Join("$usrbindir", e.Name()),
+			Perm:   packfile.PermExec,
+		})
+	}
+	return nil
+}
+
+func scanProjectEtc(context string, ctx *scaffoldContext) error {
+	return walkProjectDir(context, "etc", func(rel string) {
+		ctx.Files = append(ctx.Files, packfileResource{
+			Source: rel,
+			Target: path.Join("$etcdir", strings.TrimPrefix(rel, "etc/")),
+			Conf:   true,
+		})
+	})
+}
+
+func walkProjectDir(context, dir string, fn func(string)) error {
+	root := filepath.Join(context, filepath.FromSlash(dir))
+	err := filepath.WalkDir(root, func(file string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !e.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(context, file)
+		if err == nil {
+			fn(filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	return err
+}
+
+func readProjectManifests(context string, m *projectManifest) error {
+	readers := []struct {
+		File string
+		Read func(string) (projectManifest, error)
+	}{
+		{File: "go.mod", Read: readGoManifest},
+		{File: "Cargo.toml", Read: readCargoManifest},
+		{File: "package.json", Read: readNpmManifest},
+		{File: "pyproject.toml", Read: readPythonManifest},
+	}
+	for _, r := range readers {
+		other, err := r.Read(filepath.Join(context, r.File))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		m.merge(other)
+	}
+	return nil
+}
+
+func readGoManifest(file string) (projectManifest, error) {
+	var m projectManifest
+	r, err := os.Open(file)
+	if err != nil {
+		return m, err
+	}
+	defer r.Close()
+
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			mod := strings.Trim(fields[1], "\"")
+			if base := path.Base(mod); len(base) > 1 && base[0] == 'v' && strings.Trim(base[1:], "0123456789") == "" {
+				mod = path.Dir(mod)
+			}
+			m.Name = path.Base(mod)
+		case "go":
+			m.Compiler = packfile.Compiler{
+				Name:    "go",
+				Version: fields[1],
+			}
+		}
+	}
+	return m, scan.Err()
+}
+
+func readCargoManifest(file string) (projectManifest, error) {
+	var m projectManifest
+	doc, err := readTomlFile(file)
+	if err != nil {
+		return m, err
+	}
+	pkg := doc["package"]
+	m.Name = tomlString(pkg["name"])
+	m.Version = tomlString(pkg["version"])
+	m.Summary = tomlString(pkg["description"])
+	m.License = tomlString(pkg["license"])
+	m.Home = tomlString(pkg["homepage"])
+	if m.Home == "" {
+		m.Home = tomlString(pkg["repository"])
+	}
+	m.Maintainer = tomlAuthor(pkg["authors"])
+	if v := tomlString(pkg["rust-version"]); v != "" {
+		m.Compiler = packfile.Compiler{
+			Name:    "rust",
+			Version: v,
+		}
+	}
+	return m, nil
+}
+
+func readPythonManifest(file string) (projectManifest, error) {
+	var m projectManifest
+	doc, err := readTomlFile(file)
+	if err != nil {
+		return m, err
+	}
+	for _, section := range []string{"project", "tool.poetry"} {
+		var (
+			pkg   = doc[section]
+			other projectManifest
+		)
+		other.Name = tomlString(pkg["name"])
+		other.Version = tomlString(pkg["version"])
+		other.Summary = tomlString(pkg["description"])
+		other.License = tomlString(pkg["license"])
+		if other.License == "" {
+			other.License = tomlString(tomlTable(pkg["license"])["text"])
+		}
+		other.Home = tomlString(pkg["homepage"])
+		if other.Home == "" {
+			other.Home = tomlString(pkg["repository"])
+		}
+		other.Maintainer = tomlAuthor(pkg["authors"])
+		m.merge(other)
+	}
+	if m.Home == "" {
+		urls := doc["project.urls"]
+		for _, k := range []string{"homepage", "Homepage", "repository", "Repository"} {
+			if m.Home = tomlString(urls[k]); m.Home != "" {
+				break
+			}
+		}
+	}
+	return m, nil
+}
+
+func readNpmManifest(file string) (projectManifest, error) {
+	var (
+		m   projectManifest
+		doc struct {
+			Name        string          `json:"name"`
+			Version     string          `json:"version"`
+			Description string          `json:"description"`
+			Homepage    string          `json:"homepage"`
+			License     json.RawMessage `json:"license"`
+			Author      json.RawMessage `json:"author"`
+		}
+	)
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return m, err
+	}
+	m.Name = strings.TrimPrefix(path.Base(doc.Name), "@")
+	m.Version = doc.Version
+	m.Summary = doc.Description
+	m.Home = doc.Homepage
+
+	var (
+		str string
+		obj struct {
+			Type  string `json:"type"`
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		}
+	)
+	if json.Unmarshal(doc.License, &str) == nil {
+		m.License = str
+	} else if json.Unmarshal(doc.License, &obj) == nil {
+		m.License = obj.Type
+	}
+	str = ""
+	if json.Unmarshal(doc.Author, &str) == nil {
+		m.Maintainer = packfile.ParseMaintainer(str)
+	} else if json.Unmarshal(doc.Author, &obj) == nil {
+		m.Maintainer.Name = obj.Name
+		m.Maintainer.Email = obj.Email
+	}
+	return m, nil
+}
+
+func readTomlFile(file string) (map[string]map[string]string, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var (
+		doc     = make(map[string]map[string]string)
+		section string
+		key     string
+		value   string
+		scan    = bufio.NewScanner(r)
+	)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if key != "" {
+			value += " " + line
+			if strings.Count(value, "[") <= strings.Count(value, "]") {
+				doc[section][key], key = value, ""
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[] ")
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if doc[section] == nil {
+			doc[section] = make(map[string]string)
+		}
+		k, v = strings.Trim(strings.TrimSpace(k), "\"'"), strings.TrimSpace(v)
+		if strings.HasPrefix(v, "[") && strings.Count(v, "[") > strings.Count(v, "]") {
+			key, value = k, v
+			continue
+		}
+		doc[section][k] = v
+	}
+	return doc, scan.Err()
+}
+
+func tomlString(str string) string {
+	if len(str) < 2 || strings.HasPrefix(str, "\"\"\"") || strings.HasPrefix(str, "'''") {
+		return ""
+	}
+	switch str[0] {
+	case '\'':
+		if end := strings.IndexByte(str[1:], '\''); end >= 0 {
+			return str[1 : end+1]
+		}
+	case '"':
+		for i := 1; i < len(str); i++ {
+			switch str[i] {
+			case '\\':
+				i++
+			case '"':
+				s, err := strconv.Unquote(str[:i+1])
+				if err != nil {
+					return ""
+				}
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func tomlTable(str string) map[string]string {
+	table := make(map[string]string)
+	str = strings.TrimSpace(str)
+	if !strings.HasPrefix(str, "{") {
+		return table
+	}
+	str, _, _ = strings.Cut(str[1:], "}")
+	for _, field := range strings.Split(str, ",") {
+		k, v, ok := strings.Cut(field, "=")
+		if ok {
+			table[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return table
+}
+
+func tomlAuthor(str string) packfile.Maintainer {
+	str = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(str), "["))
+	if strings.HasPrefix(str, "{") {
+		table := tomlTable(str)
+		return packfile.Maintainer{
+			Name:  tomlString(table["name"]),
+			Email: tomlString(table["email"]),
+		}
+	}
+	return packfile.ParseMaintainer(tomlString(str))
+}
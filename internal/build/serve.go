@@ -0,0 +1,208 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/midbel/packit/internal/apk"
+	"github.com/midbel/packit/internal/deb"
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/pacman"
+	"github.com/midbel/packit/internal/rpm"
+)
+
+const ListingPath = "/packages.json"
+
+type RepoPackage struct {
+	Name    string    `json:"name"`
+	Version string    `json:"version"`
+	Release string    `json:"release,omitempty"`
+	Arch    string    `json:"arch"`
+	Type    string    `json:"type"`
+	File    string    `json:"file"`
+	Size    int64     `json:"size"`
+	Lastmod time.Time `json:"lastmod"`
+}
+
+type RepoServer struct {
+	RepoBuilder
+	Addr     string
+	Interval time.Duration
+
+	mu       sync.RWMutex
+	state    string
+	packages []RepoPackage
+}
+
+func (s *RepoServer) Serve(dir string) error {
+	if dir == "" {
+		return fmt.Errorf("no directory given")
+	}
+	if err := s.refresh(dir); err != nil {
+		return err
+	}
+	if s.Interval > 0 {
+		go s.watch(dir)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(ListingPath, s.listPackages)
+	mux.Handle("/", http.FileServer(http.Dir(dir)))
+	return http.ListenAndServe(s.Addr, mux)
+}
+
+func (s *RepoServer) watch(dir string) {
+	tick := time.NewTicker(s.Interval)
+	defer tick.Stop()
+	for range tick.C {
+		if err := s.refresh(dir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+func (s *RepoServer) refresh(dir string) error {
+	files, state, err := scanPackages(dir)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	same := s.state == state
+	s.mu.RUnlock()
+	if same {
+		return nil
+	}
+	var list []RepoPackage
+	for _, file := range files {
+		pkg, err := readRepoPackage(dir, file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		list = append(list, *pkg)
+	}
+	for _, kind := range []string{packfile.Deb, packfile.Rpm, packfile.Apk} {
+		ok := slices.ContainsFunc(list, func(p RepoPackage) bool {
+			return p.Type == kind
+		})
+		if !ok {
+			continue
+		}
+		builder := s.RepoBuilder
+		if kind == packfile.Apk {
+			builder.SignKey = ""
+		}
+		if err := builder.Build(kind, dir); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+	s.packages = list
+	return nil
+}
+
+func (s *RepoServer) listPackages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := s.packages
+	if list == nil {
+		list = []RepoPackage{}
+	}
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func scanPackages(dir string) ([]string, string, error) {
+	var (
+		files []string
+		state strings.Builder
+	)
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch getExtension(file) {
+		case ".deb", ".rpm", ".apk", pacman.Extension:
+		default:
+			return nil
+		}
+		i, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&state, "%s:%d:%d;", file, i.Size(), i.ModTime().UnixNano())
+		files = append(files, file)
+		return nil
+	})
+	return files, state.String(), err
+}
+
+func readRepoPackage(dir, file string) (*RepoPackage, error) {
+	var (
+		pkg  *packfile.Package
+		kind string
+	)
+	switch ext := getExtension(file); ext {
+	case ".deb":
+		info, err := deb.Info(file)
+		if err != nil {
+			return nil, err
+		}
+		pkg, kind = &info.Package, packfile.Deb
+	case ".rpm":
+		info, err := rpm.Info(file)
+		if err != nil {
+			return nil, err
+		}
+		pkg, kind = &info.Package, packfile.Rpm
+	case ".apk":
+		info, err := apk.Info(file)
+		if err != nil {
+			return nil, err
+		}
+		pkg, kind = &info.Package, packfile.Apk
+	case pacman.Extension:
+		info, err := pacman.Info(file)
+		if err != nil {
+			return nil, err
+		}
+		pkg, kind = &info.Package, packfile.Pacman
+	default:
+		return nil, fmt.Errorf("%s: package type not supported", ext)
+	}
+	s, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return nil, err
+	}
+	p := RepoPackage{
+		Name:    pkg.Name,
+		Version: pkg.Version,
+		Release: pkg.Release,
+		Arch:    pkg.Arch,
+		Type:    kind,
+		File:    filepath.ToSlash(rel),
+		Size:    s.Size(),
+		Lastmod: s.ModTime().UTC(),
+	}
+	return &p, nil
+}
@@ -0,0 +1,47 @@
+package build
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/midbel/packit/internal/elf"
+	"github.com/midbel/packit/internal/packfile"
+)
+
+type memFile struct {
+	*bytes.Reader
+}
+
+func (memFile) Close() error {
+	return nil
+}
+
+func stripFiles(pkg *packfile.Package) error {
+	for i, r := range pkg.Files {
+		if !pkg.Strip && !r.Strip {
+			continue
+		}
+		rs, ok := r.Local.(io.ReaderAt)
+		if !ok || !elf.IsElf(rs) {
+			continue
+		}
+		f, err := elf.NewFile(rs)
+		if err != nil {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := f.Strip(&buf); err != nil {
+			if errors.Is(err, elf.ErrStrip) {
+				continue
+			}
+			return err
+		}
+		r.Local.Close()
+
+		pkg.Files[i].Local = memFile{bytes.NewReader(buf.Bytes())}
+		pkg.Files[i].Size = int64(buf.Len())
+		pkg.Files[i].Hash = ""
+	}
+	return nil
+}
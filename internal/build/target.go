@@ -0,0 +1,154 @@
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+type Target struct {
+	Kind string
+	Arch string
+	Err  error
+
+	pkg *packfile.Package
+}
+
+func (t Target) String() string {
+	if t.Arch == "" {
+		return t.Kind
+	}
+	return fmt.Sprintf("%s/%s", t.Kind, t.Arch)
+}
+
+func (b *PackageBuilder) BuildTargets(context string, kinds, archs []string, workers int) ([]Target, error) {
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("no package type given")
+	}
+	if len(archs) == 0 {
+		archs = append(archs, "")
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	if context == "" {
+		context = filepath.Dir(b.File)
+	}
+	var targets []Target
+	for _, k := range kinds {
+		for _, a := range archs {
+			targets = append(targets, Target{Kind: k, Arch: a})
+		}
+	}
+	setup, teardown, err := b.checkTargets(context, targets)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range setup {
+		if err := runCommands(s); err != nil {
+			for _, t := range targets {
+				closeResources(t.pkg)
+			}
+			return nil, err
+		}
+	}
+
+	var (
+		queue = make(chan int)
+		group sync.WaitGroup
+	)
+	for i := 0; i < workers && i < len(targets); i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for ix := range queue {
+				builder := *b
+				builder.Type = targets[ix].Kind
+				builder.Arch = targets[ix].Arch
+				targets[ix].Err = builder.buildTarget(targets[ix].pkg)
+			}
+		}()
+	}
+	for i := range targets {
+		queue <- i
+	}
+	close(queue)
+	group.Wait()
+
+	for _, s := range teardown {
+		if err := runCommands(s); err != nil {
+			return targets, err
+		}
+	}
+	return targets, nil
+}
+
+func (b *PackageBuilder) checkTargets(context string, targets []Target) ([]string, []string, error) {
+	var (
+		setup    []string
+		teardown []string
+		names    = make(map[string]Target)
+	)
+	for i, t := range targets {
+		pkg, err := packfile.LoadTarget(b.File, context, t.Kind, t.Arch)
+		if err == nil {
+			targets[i].pkg = pkg
+			err = b.checkTarget(t, pkg, names)
+		}
+		if err != nil {
+			for _, t := range targets {
+				closeResources(t.pkg)
+			}
+			return nil, nil, err
+		}
+		if pkg.Setup != "" && !slices.Contains(setup, pkg.Setup) {
+			setup = append(setup, pkg.Setup)
+		}
+		if pkg.Teardown != "" && !slices.Contains(teardown, pkg.Teardown) {
+			teardown = append(teardown, pkg.Teardown)
+		}
+		pkg.Setup = ""
+		pkg.Teardown = ""
+	}
+	return setup, teardown, nil
+}
+
+func (b *PackageBuilder) checkTarget(t Target, pkg *packfile.Package, names map[string]Target) error {
+	if t.Arch != "" && pkg.Arch != t.Arch {
+		return fmt.Errorf("%s: architecture %s of Packfile conflicts with %s", t, pkg.Arch, t.Arch)
+	}
+	name := b.getPackageFile(t.Kind, t.Arch, pkg)
+	if other, ok := names[name]; ok {
+		return fmt.Errorf("%s: %s already built by %s", t, name, other)
+	}
+	names[name] = t
+	return nil
+}
+
+func closeResources(pkg *packfile.Package) {
+	if pkg == nil {
+		return
+	}
+	for _, r := range pkg.Files {
+		if r.Local != nil {
+			r.Local.Close()
+		}
+	}
+}
+
+func runCommands(script string) error {
+	scan := bufio.NewScanner(strings.NewReader(script))
+	for scan.Scan() {
+		cmd := exec.Command("sh", "-c", scan.Text())
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+	return scan.Err()
+}
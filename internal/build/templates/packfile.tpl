@@ -0,0 +1,100 @@
+# Packfile generated by packit
+{{with .Source}}# from {{.}}
+{{end}}
+package {{quote .Name}}
+version {{quote .Version}}
+{{with .Release}}release {{quote .}}
+{{end -}}
+{{with .Arch}}arch {{quote .}}
+{{end -}}
+{{with .Os}}os {{quote .}}
+{{end -}}
+{{with .Section}}section {{quote .}}
+{{end -}}
+{{with .Priority}}priority {{quote .}}
+{{end -}}
+{{with .Vendor}}vendor {{quote .}}
+{{end -}}
+{{with .Distrib}}distrib {{quote .}}
+{{end -}}
+{{with .Home}}home {{quote .}}
+{{end -}}
+{{with .Maintainer.Name}}
+maintainer {
+	name  {{quote .}}
+{{with $.Maintainer.Email}}	email {{quote .}}
+{{end -}}
+}
+{{end -}}
+{{with .License}}
+license {
+	type {{quote .}}
+}
+{{end -}}
+{{with .BuildWith.Name}}
+compiler {{.}} {{quote $.BuildWith.Version}}
+{{end}}
+summary {{quote .Summary}}
+{{with .Desc}}
+desc {{heredoc "DESC" .}}
+{{end -}}
+{{range $dep := .Depends}}
+{{with .Alternatives}}# alternatives not supported:{{range .}} | {{.Package}}{{end}}
+{{end -}}
+depends {
+	package {{quote .Package}}
+{{with .Type}}	type    {{quote .}}
+{{end -}}
+{{with .Arch}}	arch    {{quote .}}
+{{end -}}
+{{with .Version}}	version {{or $dep.Constraint "eq"}} {{quote .}}
+{{end -}}
+}
+{{end -}}
+{{range .Files}}
+file {
+	source {{quote .Source}}
+	target {{quote .Target}}
+	perm   {{printf "0o%o" .Perm}}
+{{if .Conf}}	conf   true
+{{end -}}
+{{if .Doc}}	doc    true
+{{end -}}
+{{if .License}}	license true
+{{end -}}
+{{if .Readme}}	readme true
+{{end -}}
+}
+{{end -}}
+{{range .Links}}
+# symbolic link not supported: {{.}}
+{{end -}}
+{{with .PreInst}}
+pre-install {{heredoc "SCRIPT" .}}
+{{end -}}
+{{with .PostInst}}
+post-install {{heredoc "SCRIPT" .}}
+{{end -}}
+{{with .PreRem}}
+pre-remove {{heredoc "SCRIPT" .}}
+{{end -}}
+{{with .PostRem}}
+post-remove {{heredoc "SCRIPT" .}}
+{{end -}}
+{{range $change := .Changes}}
+changelog {
+{{with .Version}}	version {{quote .}}
+{{end -}}
+{{if not .When.IsZero}}	date    {{quote (.When.Format "2006-01-02")}}
+{{end -}}
+{{with .Summary}}	summary {{quote .}}
+{{end -}}
+{{range .Changes}}	change  {{quote .}}
+{{end -}}
+{{with .Maintainer.Name}}	maintainer {
+		name  {{quote .}}
+{{with $change.Maintainer.Email}}		email {{quote .}}
+{{end}}	}
+{{end -}}
+}
+{{end -}}
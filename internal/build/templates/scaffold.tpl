@@ -0,0 +1,42 @@
+# Packfile generated by packit
+.let package {{quote .Name}}
+
+package $package
+version {{quote .Version}}
+{{with .Home}}home {{quote .}}
+{{end -}}
+{{with .Summary}}summary {{quote .}}
+{{end -}}
+{{with .Maintainer.Name}}
+maintainer {
+	name  {{quote .}}
+{{with $.Maintainer.Email}}	email {{quote .}}
+{{end -}}
+}
+{{end -}}
+{{if .LicenseTemplate}}
+license {{.LicenseTemplate}}
+{{else if .License}}
+license {
+	type {{quote .License}}
+}
+{{end -}}
+{{with .Compiler.Name}}
+compiler {{.}} {{quote $.Compiler.Version}}
+{{end -}}
+{{range .Files}}
+file {
+	source {{quote .Source}}
+	target `{{.Target}}`
+{{with .Perm}}	perm   {{printf "0o%o" .}}
+{{end -}}
+{{if .Conf}}	conf   true
+{{end -}}
+{{if .Doc}}	doc    true
+{{end -}}
+{{if .License}}	license true
+{{end -}}
+{{if .Readme}}	readme true
+{{end -}}
+}
+{{end -}}
@@ -0,0 +1,76 @@
+package deb
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+const changelogTime = "Mon, 02 Jan 2006 15:04:05 -0700"
+
+func Changelog(file string) ([]packfile.Change, string, error) {
+	var (
+		list   []packfile.Change
+		target string
+	)
+	err := Payload(file, func(h *tar.Header, r io.Reader) error {
+		if h.Typeflag != tar.TypeReg || target != "" {
+			return nil
+		}
+		switch path.Base(h.Name) {
+		case changelogFile, "changelog.Debian.gz":
+		default:
+			return nil
+		}
+		z, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		if list, err = parseChangelog(z); err == nil {
+			target = strings.TrimLeft(strings.TrimPrefix(h.Name, "./"), "/")
+		}
+		return err
+	})
+	return list, target, err
+}
+
+func parseChangelog(r io.Reader) ([]packfile.Change, error) {
+	var (
+		scan = bufio.NewScanner(r)
+		list []packfile.Change
+		curr *packfile.Change
+	)
+	for scan.Scan() {
+		line := scan.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+		case !strings.HasPrefix(line, " "):
+			list = append(list, packfile.Change{})
+			curr = &list[len(list)-1]
+			if _, rest, ok := strings.Cut(line, "("); ok {
+				curr.Version, _, _ = strings.Cut(rest, ")")
+			}
+		case curr == nil:
+		case strings.HasPrefix(line, " -- "):
+			who, when, _ := strings.Cut(strings.TrimPrefix(line, " -- "), "  ")
+			curr.Maintainer = packfile.ParseMaintainer(who)
+			curr.When, _ = time.Parse(changelogTime, strings.TrimSpace(when))
+		case strings.HasPrefix(strings.TrimSpace(line), "* "):
+			curr.Changes = append(curr.Changes, strings.TrimPrefix(strings.TrimSpace(line), "* "))
+		case len(curr.Changes) > 0:
+			last := len(curr.Changes) - 1
+			curr.Changes[last] += " " + strings.TrimSpace(line)
+		case curr.Summary == "":
+			curr.Summary = strings.TrimSpace(line)
+		default:
+			curr.Summary += " " + strings.TrimSpace(line)
+		}
+	}
+	return list, scan.Err()
+}
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/pgp"
 	"github.com/midbel/tape"
 	"github.com/midbel/tape/ar"
 	"github.com/midbel/textwrap"
@@ -47,7 +48,11 @@ var aboutFile string
 var changeFile string
 
 type DebBuilder struct {
-	writer *ar.Writer
+	writer    *ar.Writer
+	buildTime time.Time
+
+	key  *pgp.Key
+	role string
 }
 
 func Build(w io.Writer) (*DebBuilder, error) {
@@ -56,11 +61,16 @@ func Build(w io.Writer) (*DebBuilder, error) {
 		return nil, err
 	}
 	b := DebBuilder{
-		writer: wr,
+		writer:    wr,
+		buildTime: time.Now(),
 	}
 	return &b, nil
 }
 
+func (d *DebBuilder) SetSourceDateEpoch(when time.Time) {
+	d.buildTime = when
+}
+
 func (d DebBuilder) Build(p *packfile.Package) error {
 	if err := d.setup(p); err != nil {
 		return err
@@ -100,17 +110,19 @@ func (d DebBuilder) teardown(pkg *packfile.Package) error {
 }
 
 func (d DebBuilder) build(p *packfile.Package) error {
-	defer func() {
-		os.Remove(ControlFile)
-		os.Remove(DataFile)
-	}()
-	data, err := writeFiles(p)
+	dir, err := os.MkdirTemp("", "packit-deb-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := writeFiles(p, dir, d.buildTime)
 	if err != nil {
 		return err
 	}
 	defer data.Close()
 
-	ctrl, err := writeControl(p)
+	ctrl, err := writeControl(p, dir, d.buildTime)
 	if err != nil {
 		return err
 	}
@@ -122,7 +134,13 @@ func (d DebBuilder) build(p *packfile.Package) error {
 	if err := d.writeControl(ctrl); err != nil {
 		return err
 	}
-	return d.writeData(data)
+	if err := d.writeData(data); err != nil {
+		return err
+	}
+	if d.key == nil {
+		return nil
+	}
+	return d.writeSignature(ctrl, data)
 }
 
 func (d DebBuilder) writeDebian() error {
@@ -132,7 +150,7 @@ func (d DebBuilder) writeDebian() error {
 		Gid:      0,
 		Mode:     0644,
 		Size:     int64(len(debVersion)),
-		ModTime:  time.Now(),
+		ModTime:  d.buildTime,
 	}
 	if err := d.writer.WriteHeader(&h); err != nil {
 		return err
@@ -149,6 +167,10 @@ func (d DebBuilder) writeControl(r *os.File) error {
 	if err != nil {
 		return err
 	}
+	h.Filename = ControlFile
+	h.Uid, h.Gid = 0, 0
+	h.Mode = packfile.PermFile
+	h.ModTime = d.buildTime
 	if err := d.writer.WriteHeader(h); err != nil {
 		return err
 	}
@@ -164,6 +186,10 @@ func (d DebBuilder) writeData(r *os.File) error {
 	if err != nil {
 		return err
 	}
+	h.Filename = DataFile
+	h.Uid, h.Gid = 0, 0
+	h.Mode = packfile.PermFile
+	h.ModTime = d.buildTime
 	if err := d.writer.WriteHeader(h); err != nil {
 		return err
 	}
@@ -175,7 +201,7 @@ func (d DebBuilder) Close() error {
 	return d.writer.Close()
 }
 
-func writeSpec(w *tar.Writer, pkg *packfile.Package) error {
+func writeSpec(w *tar.Writer, pkg *packfile.Package, when time.Time) error {
 	var buf bytes.Buffer
 
 	fn := template.FuncMap{
@@ -201,7 +227,7 @@ func writeSpec(w *tar.Writer, pkg *packfile.Package) error {
 		return r
 	}, buf.String())
 
-	h := makeTarHeader(controlFile, len(str), packfile.PermFile)
+	h := makeTarHeader(controlFile, len(str), packfile.PermFile, when)
 	if err := w.WriteHeader(h); err != nil {
 		return err
 	}
@@ -209,16 +235,19 @@ func writeSpec(w *tar.Writer, pkg *packfile.Package) error {
 	return err
 }
 
-func writeChecksums(w *tar.Writer, pkg *packfile.Package) error {
+func writeChecksums(w *tar.Writer, pkg *packfile.Package, when time.Time) error {
 	if len(pkg.Files) == 0 {
 		return nil
 	}
 	var str bytes.Buffer
 	for _, r := range pkg.Files {
+		if r.IsDirectory() || r.IsSymlink() {
+			continue
+		}
 		io.WriteString(&str, fmt.Sprintf("%s  %s\n", r.Hash, r.Target))
 	}
 
-	h := makeTarHeader(md5File, str.Len(), packfile.PermFile)
+	h := makeTarHeader(md5File, str.Len(), packfile.PermFile, when)
 	if err := w.WriteHeader(h); err != nil {
 		return err
 	}
@@ -226,7 +255,7 @@ func writeChecksums(w *tar.Writer, pkg *packfile.Package) error {
 	return err
 }
 
-func writeConffiles(w *tar.Writer, pkg *packfile.Package) error {
+func writeConffiles(w *tar.Writer, pkg *packfile.Package, when time.Time) error {
 	if len(pkg.Files) == 0 {
 		return nil
 	}
@@ -241,7 +270,7 @@ func writeConffiles(w *tar.Writer, pkg *packfile.Package) error {
 	if str.Len() == 0 {
 		return nil
 	}
-	h := makeTarHeader(confFile, str.Len(), packfile.PermFile)
+	h := makeTarHeader(confFile, str.Len(), packfile.PermFile, when)
 	if err := w.WriteHeader(h); err != nil {
 		return err
 	}
@@ -249,8 +278,8 @@ func writeConffiles(w *tar.Writer, pkg *packfile.Package) error {
 	return err
 }
 
-func writeControl(pkg *packfile.Package) (*os.File, error) {
-	f, err := os.Create(ControlFile)
+func writeControl(pkg *packfile.Package, dir string, when time.Time) (*os.File, error) {
+	f, err := os.Create(filepath.Join(dir, ControlFile))
 	if err != nil {
 		return nil, err
 	}
@@ -261,31 +290,31 @@ func writeControl(pkg *packfile.Package) (*os.File, error) {
 	w := tar.NewWriter(ws)
 	defer w.Close()
 
-	if err := writeSpec(w, pkg); err != nil {
+	if err := writeSpec(w, pkg, when); err != nil {
 		f.Close()
 		return nil, err
 	}
-	if err := writeChecksums(w, pkg); err != nil {
+	if err := writeChecksums(w, pkg, when); err != nil {
 		f.Close()
 		return nil, err
 	}
-	if err := writeConffiles(w, pkg); err != nil {
+	if err := writeConffiles(w, pkg, when); err != nil {
 		f.Close()
 		return nil, err
 	}
-	if err := writeScripts(w, pkg); err != nil {
+	if err := writeScripts(w, pkg, when); err != nil {
 		f.Close()
 		return nil, err
 	}
 	return f, nil
 }
 
-func writeScripts(w *tar.Writer, pkg *packfile.Package) error {
+func writeScripts(w *tar.Writer, pkg *packfile.Package, when time.Time) error {
 	write := func(script, file string) error {
 		if script == "" {
 			return nil
 		}
-		h := makeTarHeader(file, len(script), packfile.PermExec)
+		h := makeTarHeader(file, len(script), packfile.PermExec, when)
 		if err := w.WriteHeader(h); err != nil {
 			return err
 		}
@@ -322,8 +351,8 @@ func writeScripts(w *tar.Writer, pkg *packfile.Package) error {
 	return nil
 }
 
-func writeFiles(pkg *packfile.Package) (*os.File, error) {
-	f, err := os.Create(DataFile)
+func writeFiles(pkg *packfile.Package, tmpdir string, when time.Time) (*os.File, error) {
+	f, err := os.Create(filepath.Join(tmpdir, DataFile))
 	if err != nil {
 		return nil, err
 	}
@@ -341,7 +370,7 @@ func writeFiles(pkg *packfile.Package) (*os.File, error) {
 		return strings.Compare(a.Target, b.Target)
 	})
 	if len(pkg.Changes) > 0 {
-		res, err := writeChangelog(pkg)
+		res, err := writeChangelog(pkg, tmpdir, when)
 		if err != nil {
 			f.Close()
 			return nil, err
@@ -365,20 +394,49 @@ func writeFiles(pkg *packfile.Package) (*os.File, error) {
 					continue
 				}
 				seen[target] = struct{}{}
-				h := makeTarHeaderDir(strings.Join(paths[:i+1], "/"))
+				h := makeTarHeaderDir(strings.Join(paths[:i+1], "/"), when)
 				if err := w.WriteHeader(h); err != nil {
 					f.Close()
 					return nil, err
 				}
 			}
 		}
+		if r.IsDirectory() {
+			if _, ok := seen[r.Target]; ok {
+				continue
+			}
+			seen[r.Target] = struct{}{}
+			h := makeTarHeaderDir(r.Target, r.Lastmod)
+			if r.Perm != 0 {
+				h.Mode = r.Perm | int64(os.ModeDir)
+			}
+			if err := w.WriteHeader(h); err != nil {
+				f.Close()
+				return nil, err
+			}
+			continue
+		}
+		if r.IsSymlink() {
+			h := makeTarHeader(r.Target, 0, packfile.PermFile, r.Lastmod)
+			h.Typeflag = tar.TypeSymlink
+			h.Linkname = r.Link
+			h.Mode = 0o777
+			if err := w.WriteHeader(h); err != nil {
+				f.Close()
+				return nil, err
+			}
+			continue
+		}
 		if r.Compress {
 			// TODO
 		}
-		var (
-			sum  = md5.New()
+		perm := r.Perm
+		if perm == 0 {
 			perm = packfile.GetPermissionFromPath(r.Target)
-			h    = makeTarHeader(r.Target, int(r.Size), int(perm))
+		}
+		var (
+			sum = md5.New()
+			h   = makeTarHeader(r.Target, int(r.Size), int(perm), r.Lastmod)
 		)
 		if err := w.WriteHeader(h); err != nil {
 			f.Close()
@@ -395,11 +453,11 @@ func writeFiles(pkg *packfile.Package) (*os.File, error) {
 	return f, nil
 }
 
-func writeChangelog(pkg *packfile.Package) (packfile.Resource, error) {
+func writeChangelog(pkg *packfile.Package, dir string, when time.Time) (packfile.Resource, error) {
 	res := packfile.Resource{
 		Target:  pkg.GetDirDoc(changelogFile),
 		Perm:    packfile.PermFile,
-		Lastmod: time.Now(),
+		Lastmod: when,
 	}
 
 	tpl, err := template.New("changelog").Parse(changeFile)
@@ -407,7 +465,7 @@ func writeChangelog(pkg *packfile.Package) (packfile.Resource, error) {
 		return res, err
 	}
 
-	f, err := os.CreateTemp("", changelogFile)
+	f, err := os.Create(filepath.Join(dir, changelogFile))
 	if err != nil {
 		return res, err
 	}
@@ -426,21 +484,24 @@ func writeChangelog(pkg *packfile.Package) (packfile.Resource, error) {
 	return res, nil
 }
 
-func makeTarHeaderDir(file string) *tar.Header {
-	h := makeTarHeader(file, 0, packfile.PermDir)
+func makeTarHeaderDir(file string, when time.Time) *tar.Header {
+	h := makeTarHeader(file, 0, packfile.PermDir, when)
 	h.Typeflag = tar.TypeDir
 	h.Mode |= int64(os.ModeDir)
 	return h
 }
 
-func makeTarHeader(file string, size, perm int) *tar.Header {
+func makeTarHeader(file string, size, perm int, when time.Time) *tar.Header {
+	if when.IsZero() {
+		when = time.Now()
+	}
 	h := tar.Header{
 		Typeflag: tar.TypeReg,
 		Name:     file,
 		Size:     int64(size),
 		Uid:      0,
 		Gid:      0,
-		ModTime:  time.Now(),
+		ModTime:  when,
 		Mode:     int64(perm),
 	}
 	h.ChangeTime = h.ModTime
@@ -474,7 +535,7 @@ func formatPackageDesc(str string) string {
 	return wr.String()
 }
 
-func formatDependency(dp packfile.Dependency) string {
+func formatDependency(dp packfile.Dependency) (string, error) {
 	var str strings.Builder
 	str.WriteString(dp.Package)
 	if dp.Arch != "" {
@@ -482,32 +543,41 @@ func formatDependency(dp packfile.Dependency) string {
 		str.WriteString(dp.Arch)
 	}
 	if dp.Version != "" {
+		op, err := formatDependencyConstraint(dp.Constraint)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", dp.Package, err)
+		}
 		str.WriteRune(' ')
 		str.WriteRune('(')
-		str.WriteString(formatDependencyConstraint(dp.Constraint))
+		str.WriteString(op)
 		str.WriteRune(' ')
 		str.WriteString(dp.Version)
 		str.WriteRune(')')
 	}
-	return str.String()
+	for _, alt := range dp.Alternatives {
+		other, err := formatDependency(alt)
+		if err != nil {
+			return "", err
+		}
+		str.WriteString(" | ")
+		str.WriteString(other)
+	}
+	return str.String(), nil
 }
 
-func formatDependencyConstraint(op string) string {
+func formatDependencyConstraint(op string) (string, error) {
 	switch op {
 	case packfile.ConstraintEq:
-		op = "="
-	case packfile.ConstraintNe:
-		op = "!="
+		return "=", nil
 	case packfile.ConstraintGt:
-		op = ">"
+		return ">>", nil
 	case packfile.ConstraintGe, "":
-		op = ">="
+		return ">=", nil
 	case packfile.ConstraintLt:
-		op = "<"
+		return "<<", nil
 	case packfile.ConstraintLe:
-		op = "<="
+		return "<=", nil
 	default:
-		op = ""
+		return "", fmt.Errorf("%s: constraint not supported by deb packages", op)
 	}
-	return op
 }
@@ -3,6 +3,7 @@ package deb
 import (
 	"archive/tar"
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -12,60 +13,99 @@ import (
 
 	"github.com/midbel/packit/internal/packfile"
 	"github.com/midbel/tape"
-	"github.com/midbel/tape/ar"
 )
 
 func Content(file string) ([]*tape.Header, error) {
+	var list []*tape.Header
+	err := Payload(file, func(h *tar.Header, _ io.Reader) error {
+		hdr := tape.Header{
+			Filename: h.Name,
+			Size:     h.Size,
+			Mode:     h.Mode,
+			Uid:      int64(h.Uid),
+			Gid:      int64(h.Gid),
+			ModTime:  h.ModTime,
+		}
+		if h.Typeflag == tar.TypeDir {
+			hdr.Mode |= int64(os.ModeDir)
+		}
+		list = append(list, &hdr)
+		return nil
+	})
+	return list, err
+}
+
+func Payload(file string, fn func(*tar.Header, io.Reader) error) error {
 	r, err := os.Open(file)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer r.Close()
 
-	rs, err := ar.NewReader(r)
+	rs, err := newReader(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if err := readDebian(rs); err != nil {
-		return nil, err
+		return err
 	}
 	h, err := rs.Next()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if _, err = io.Copy(io.Discard, io.LimitReader(rs, h.Size)); err != nil {
-		return nil, err
+		return err
 	}
 	dt, err := openFile(rs, DataFile)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	var list []*tape.Header
 	for {
 		h, err := dt.Next()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return nil, err
+			return err
 		}
-		if _, err := io.Copy(io.Discard, io.LimitReader(dt, h.Size)); err != nil {
-			return nil, err
+		if err := fn(h, dt); err != nil {
+			return err
 		}
-		hdr := tape.Header{
-			Filename: h.Name,
-			Size:     h.Size,
-			Mode:     h.Mode,
-			Uid:      int64(h.Uid),
-			Gid:      int64(h.Gid),
-			ModTime:  h.ModTime,
+	}
+	return nil
+}
+
+func ControlMembers(file string, fn func(*tar.Header, io.Reader) error) error {
+	r, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	rs, err := newReader(r)
+	if err != nil {
+		return err
+	}
+	if err := readDebian(rs); err != nil {
+		return err
+	}
+	ct, err := openFile(rs, ControlFile)
+	if err != nil {
+		return err
+	}
+	for {
+		h, err := ct.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
 		}
-		if h.Typeflag == tar.TypeDir {
-			hdr.Mode |= int64(os.ModeDir)
+		if err := fn(h, ct); err != nil {
+			return err
 		}
-		list = append(list, &hdr)
 	}
-	return list, nil
+	return nil
 }
 
 type PackageInfo struct {
@@ -74,13 +114,21 @@ type PackageInfo struct {
 }
 
 func Info(file string) (*PackageInfo, error) {
+	control, err := Control(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseControl(bytes.NewReader(control))
+}
+
+func Control(file string) ([]byte, error) {
 	r, err := os.Open(file)
 	if err != nil {
 		return nil, err
 	}
 	defer r.Close()
 
-	rs, err := ar.NewReader(r)
+	rs, err := newReader(r)
 	if err != nil {
 		return nil, err
 	}
@@ -91,34 +139,81 @@ func Info(file string) (*PackageInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	return parseControl(control)
+	return io.ReadAll(control)
 }
 
-func Dependencies(file string) ([]string, error) {
-	return nil, nil
+func Dependencies(file string) ([]packfile.Dependency, error) {
+	pkg, err := Info(file)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.Depends, nil
 }
 
-func parseControl(r io.Reader) (*PackageInfo, error) {
+type controlField struct {
+	Name  string
+	Value string
+	Lines []string
+}
+
+func (f controlField) Text() string {
+	return strings.TrimSpace(strings.Join(append([]string{f.Value}, f.Lines...), " "))
+}
+
+func readControlFields(r io.Reader) ([]controlField, error) {
 	var (
-		scan = bufio.NewScanner(r)
-		pkg  PackageInfo
+		scan   = bufio.NewScanner(r)
+		fields []controlField
 	)
 	for scan.Scan() {
 		line := scan.Text()
-		if line == "" {
+		if strings.TrimSpace(line) == "" {
+			if len(fields) == 0 {
+				continue
+			}
 			break
 		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("invalid control file: continuation line without field %s", line)
+			}
+			last := &fields[len(fields)-1]
+			last.Lines = append(last.Lines, strings.TrimSpace(line))
+			continue
+		}
 		field, value, ok := strings.Cut(line, ":")
 		if !ok {
 			return nil, fmt.Errorf("invalid control file: missing colon in line %s", line)
 		}
-		value = strings.TrimSpace(value)
-		switch strings.ToLower(field) {
+		fields = append(fields, controlField{
+			Name:  strings.TrimSpace(field),
+			Value: strings.TrimSpace(value),
+		})
+	}
+	return fields, scan.Err()
+}
+
+func parseControl(r io.Reader) (*PackageInfo, error) {
+	fields, err := readControlFields(r)
+	if err != nil {
+		return nil, err
+	}
+	var pkg PackageInfo
+	for _, f := range fields {
+		value := f.Text()
+		switch field := strings.ToLower(f.Name); field {
 		case "package":
 			pkg.Name = value
 		case "version":
 			pkg.Version = value
 		case "maintainer":
+			pkg.Maintainer = packfile.ParseMaintainer(value)
+		case "essential":
+			pkg.Essential = value == "yes"
+		case "homepage":
+			pkg.Home = value
+		case "vendor":
+			pkg.Vendor = value
 		case "section":
 			pkg.Section = value
 		case "priority":
@@ -126,7 +221,9 @@ func parseControl(r io.Reader) (*PackageInfo, error) {
 		case "architecture":
 			pkg.Arch = value
 		case "built-using":
-			pkg.BuildWith.Name = value
+			pkg.BuildWith = parseCompiler(value)
+		case "depends", "pre-depends", "recommends", "suggests", "breaks", "conflicts", "replaces", "enhances", "provides":
+			pkg.Depends = append(pkg.Depends, parseDependencies(value, field)...)
 		case "installed-size":
 			size, err := strconv.ParseInt(value, 10, 64)
 			if err != nil {
@@ -134,17 +231,13 @@ func parseControl(r io.Reader) (*PackageInfo, error) {
 			}
 			pkg.Size = size
 		case "description":
-			pkg.Summary = value
+			pkg.Summary = f.Value
 			var lines []string
-			for scan.Scan() {
-				line := strings.TrimSpace(scan.Text())
-				if line == "" {
-					break
-				}
+			for _, line := range f.Lines {
 				if line == "." {
 					line = ""
 				}
-				lines = append(lines, strings.TrimSpace(line))
+				lines = append(lines, line)
 			}
 			lines = append(lines, "")
 			pkg.Desc = strings.Join(lines, "\n")
@@ -153,3 +246,81 @@ func parseControl(r io.Reader) (*PackageInfo, error) {
 	}
 	return &pkg, nil
 }
+
+func parseCompiler(str string) packfile.Compiler {
+	var (
+		c             packfile.Compiler
+		name, vers, _ = strings.Cut(str, "(")
+	)
+	c.Name = strings.TrimSpace(name)
+	vers = strings.TrimSuffix(strings.TrimSpace(vers), ")")
+	c.Version = strings.TrimLeft(vers, "=<> ")
+	return c
+}
+
+func parseDependencies(str, kind string) []packfile.Dependency {
+	var list []packfile.Dependency
+	for _, str := range strings.Split(str, ",") {
+		var alts []packfile.Dependency
+		for _, str := range strings.Split(str, "|") {
+			str = strings.TrimSpace(str)
+			if str == "" {
+				continue
+			}
+			dep := parseDependency(str)
+			dep.Type = kind
+			alts = append(alts, dep)
+		}
+		if len(alts) == 0 {
+			continue
+		}
+		dep := alts[0]
+		if len(alts) > 1 {
+			dep.Alternatives = alts[1:]
+		}
+		list = append(list, dep)
+	}
+	return list
+}
+
+func parseDependency(str string) packfile.Dependency {
+	var (
+		dep            packfile.Dependency
+		name, vers, ok = strings.Cut(str, "(")
+	)
+	dep.Package = strings.TrimSpace(name)
+	if name, arch, found := strings.Cut(dep.Package, ":"); found {
+		dep.Package = name
+		dep.Arch = arch
+	}
+	if ok {
+		vers, _, _ = strings.Cut(vers, ")")
+		vers = strings.TrimSpace(vers)
+		ix := strings.IndexFunc(vers, func(r rune) bool {
+			return r != '<' && r != '>' && r != '='
+		})
+		if ix > 0 {
+			dep.Constraint = parseDependencyConstraint(vers[:ix])
+			dep.Version = strings.TrimSpace(vers[ix:])
+		}
+	}
+	return dep
+}
+
+func parseDependencyConstraint(op string) string {
+	switch op {
+	case "=":
+		op = packfile.ConstraintEq
+	case ">>":
+		op = packfile.ConstraintGt
+	case ">=", ">":
+		op = packfile.ConstraintGe
+	case "<<":
+		op = packfile.ConstraintLt
+	case "<=", "<":
+		op = packfile.ConstraintLe
+	default:
+		op = ""
+	}
+	return op
+}
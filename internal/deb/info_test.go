@@ -0,0 +1,128 @@
+package deb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+func TestParseDependencies(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []packfile.Dependency
+	}{
+		{
+			input: "",
+			want:  nil,
+		},
+		{
+			input: "libc6",
+			want: []packfile.Dependency{
+				{Package: "libc6", Type: "depends"},
+			},
+		},
+		{
+			input: "libc6 (>= 2.34), zlib1g (= 1:1.2.13)",
+			want: []packfile.Dependency{
+				{Package: "libc6", Constraint: packfile.ConstraintGe, Version: "2.34", Type: "depends"},
+				{Package: "zlib1g", Constraint: packfile.ConstraintEq, Version: "1:1.2.13", Type: "depends"},
+			},
+		},
+		{
+			input: "foo (>> 1.0),bar (<< 2.0-1), baz (<= 3)",
+			want: []packfile.Dependency{
+				{Package: "foo", Constraint: packfile.ConstraintGt, Version: "1.0", Type: "depends"},
+				{Package: "bar", Constraint: packfile.ConstraintLt, Version: "2.0-1", Type: "depends"},
+				{Package: "baz", Constraint: packfile.ConstraintLe, Version: "3", Type: "depends"},
+			},
+		},
+		{
+			input: "python3:any (>= 3.9)",
+			want: []packfile.Dependency{
+				{Package: "python3", Arch: "any", Constraint: packfile.ConstraintGe, Version: "3.9", Type: "depends"},
+			},
+		},
+		{
+			input: "mail-transport-agent | postfix (>= 3.0) | exim4, ,",
+			want: []packfile.Dependency{
+				{
+					Package: "mail-transport-agent",
+					Type:    "depends",
+					Alternatives: []packfile.Dependency{
+						{Package: "postfix", Constraint: packfile.ConstraintGe, Version: "3.0", Type: "depends"},
+						{Package: "exim4", Type: "depends"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := parseDependencies(tt.input, "depends")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: want %+v, got %+v", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestParseControl(t *testing.T) {
+	tests := []struct {
+		input   string
+		name    string
+		depends []string
+		summary string
+		desc    string
+		home    string
+	}{
+		{
+			input:   "Package: foo\nVersion: 1.0\nDescription: short\n",
+			name:    "foo",
+			summary: "short",
+			desc:    "",
+		},
+		{
+			input:   "Package: foo\nDepends: libc6 (>= 2.34),\n zlib1g,\n\tlibssl3\nDescription: short\n first line\n .\n second line\nHomepage: https://example.org\n",
+			name:    "foo",
+			depends: []string{"libc6", "zlib1g", "libssl3"},
+			summary: "short",
+			desc:    "first line\n\nsecond line\n",
+			home:    "https://example.org",
+		},
+		{
+			input:   "Package: foo\nDepends:\n libc6,\n zlib1g\n\nPackage: bar\n",
+			name:    "foo",
+			depends: []string{"libc6", "zlib1g"},
+		},
+	}
+	for _, tt := range tests {
+		pkg, err := parseControl(strings.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.input, err)
+			continue
+		}
+		if pkg.Name != tt.name || pkg.Summary != tt.summary || pkg.Desc != tt.desc || pkg.Home != tt.home {
+			t.Errorf("%q: fields mismatched (%q %q %q %q)", tt.input, pkg.Name, pkg.Summary, pkg.Desc, pkg.Home)
+		}
+		var depends []string
+		for _, d := range pkg.Depends {
+			depends = append(depends, d.Package)
+		}
+		if !reflect.DeepEqual(depends, tt.depends) {
+			t.Errorf("%q: want depends %v, got %v", tt.input, tt.depends, depends)
+		}
+	}
+}
+
+func TestParseControlInvalid(t *testing.T) {
+	tests := []string{
+		" continuation\nPackage: foo\n",
+		"Package: foo\nVersion 1.0\n",
+		"Package: foo\nInstalled-Size: abc\n",
+	}
+	for _, input := range tests {
+		if _, err := parseControl(strings.NewReader(input)); err == nil {
+			t.Errorf("%q: expected error", input)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package deb
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/pgp"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+type IpkBuilder struct {
+	DebBuilder
+
+	zip    *gzip.Writer
+	writer *tar.Writer
+}
+
+func BuildIpk(w io.Writer) (*IpkBuilder, error) {
+	z, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+	b := IpkBuilder{
+		DebBuilder: DebBuilder{
+			buildTime: time.Now(),
+		},
+		zip:    z,
+		writer: tar.NewWriter(z),
+	}
+	return &b, nil
+}
+
+func (i IpkBuilder) Build(p *packfile.Package) error {
+	if err := i.setup(p); err != nil {
+		return err
+	}
+	if err := i.build(p); err != nil {
+		return err
+	}
+	return i.teardown(p)
+}
+
+func (i IpkBuilder) build(p *packfile.Package) error {
+	dir, err := os.MkdirTemp("", "packit-ipk-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := writeFiles(p, dir, i.buildTime)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	ctrl, err := writeControl(p, dir, i.buildTime)
+	if err != nil {
+		return err
+	}
+	defer ctrl.Close()
+
+	if err := i.writeDebian(); err != nil {
+		return err
+	}
+	if err := i.writeFile(ctrl); err != nil {
+		return err
+	}
+	return i.writeFile(data)
+}
+
+func (i IpkBuilder) writeDebian() error {
+	h := makeTarHeader("./"+debianFile, len(debVersion), packfile.PermFile, i.buildTime)
+	if err := i.writer.WriteHeader(h); err != nil {
+		return err
+	}
+	_, err := io.WriteString(i.writer, debVersion)
+	return err
+}
+
+func (i IpkBuilder) writeFile(r *os.File) error {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s, err := r.Stat()
+	if err != nil {
+		return err
+	}
+	h := makeTarHeader("./"+s.Name(), int(s.Size()), packfile.PermFile, i.buildTime)
+	if err := i.writer.WriteHeader(h); err != nil {
+		return err
+	}
+	_, err = io.Copy(i.writer, r)
+	return err
+}
+
+func (i *IpkBuilder) SignWith(_ *pgp.Key, _ string) error {
+	return fmt.Errorf("ipk: package signing not supported")
+}
+
+func (i IpkBuilder) Close() error {
+	if err := i.writer.Close(); err != nil {
+		return err
+	}
+	return i.zip.Close()
+}
@@ -0,0 +1,144 @@
+package deb
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+func Load(file string) (*packfile.Package, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	rs, err := newReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := readDebian(rs); err != nil {
+		return nil, err
+	}
+	pkg, conffiles, err := loadControl(rs)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadFiles(rs, pkg); err != nil {
+		return nil, err
+	}
+	for i, r := range pkg.Files {
+		if slices.Contains(conffiles, r.Target) {
+			pkg.Files[i].Flags |= packfile.FileFlagConf
+		}
+	}
+	return pkg, nil
+}
+
+func loadControl(r archiveReader) (*packfile.Package, []string, error) {
+	rs, err := openFile(r, ControlFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		pkg       *packfile.Package
+		conffiles []string
+		scripts   = make(map[string]string)
+	)
+	for {
+		h, err := rs.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, nil, err
+		}
+		var tmp bytes.Buffer
+		if _, err := io.Copy(&tmp, io.LimitReader(rs, h.Size)); err != nil {
+			return nil, nil, err
+		}
+		switch name := strings.TrimPrefix(h.Name, "./"); name {
+		case controlFile:
+			info, err := parseControl(&tmp)
+			if err != nil {
+				return nil, nil, err
+			}
+			pkg = &info.Package
+		case confFile:
+			scan := bufio.NewScanner(&tmp)
+			for scan.Scan() {
+				line := strings.TrimSpace(scan.Text())
+				if line == "" {
+					continue
+				}
+				conffiles = append(conffiles, strings.TrimPrefix(line, "/"))
+			}
+		case preinstFile, postinstFile, prermFile, postrmFile:
+			scripts[name] = tmp.String()
+		default:
+		}
+	}
+	if pkg == nil {
+		return nil, nil, fmt.Errorf("file %s not found in %s", controlFile, ControlFile)
+	}
+	pkg.PreInst = scripts[preinstFile]
+	pkg.PostInst = scripts[postinstFile]
+	pkg.PreRem = scripts[prermFile]
+	pkg.PostRem = scripts[postrmFile]
+	return pkg, conffiles, nil
+}
+
+func loadFiles(r archiveReader, pkg *packfile.Package) error {
+	rs, err := openFile(r, DataFile)
+	if err != nil {
+		return err
+	}
+	for {
+		h, err := rs.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		target := strings.TrimPrefix(h.Name, "./")
+		res := packfile.Resource{
+			Path:    target,
+			Target:  strings.TrimSuffix(strings.TrimPrefix(target, "/"), "/"),
+			Perm:    h.Mode & 0o7777,
+			Lastmod: h.ModTime,
+		}
+		switch h.Typeflag {
+		case tar.TypeReg:
+			var tmp bytes.Buffer
+			if _, err := io.Copy(&tmp, io.LimitReader(rs, h.Size)); err != nil {
+				return err
+			}
+			res.Local = io.NopCloser(&tmp)
+			res.Size = h.Size
+		case tar.TypeDir:
+			if res.Target == "" {
+				continue
+			}
+			res.Flags |= packfile.FileFlagDir
+		case tar.TypeSymlink:
+			res.Link = h.Linkname
+		default:
+			continue
+		}
+		if strings.HasPrefix(res.Target, packfile.DirDoc) {
+			res.Flags |= packfile.FileFlagDoc
+		}
+		pkg.Files = append(pkg.Files, res)
+	}
+	pkg.Files = packfile.PruneDirs(pkg.Files)
+	return nil
+}
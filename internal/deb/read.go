@@ -2,15 +2,59 @@ package deb
 
 import (
 	"archive/tar"
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/midbel/tape"
 	"github.com/midbel/tape/ar"
 )
 
-func readDebian(r *ar.Reader) error {
+type archiveReader interface {
+	io.Reader
+	Next() (*tape.Header, error)
+}
+
+type ipkReader struct {
+	*tar.Reader
+}
+
+func (r ipkReader) Next() (*tape.Header, error) {
+	h, err := r.Reader.Next()
+	if err != nil {
+		return nil, err
+	}
+	hdr := tape.Header{
+		Filename: strings.TrimPrefix(h.Name, "./"),
+		Size:     h.Size,
+		Mode:     h.Mode,
+		Uid:      int64(h.Uid),
+		Gid:      int64(h.Gid),
+		ModTime:  h.ModTime,
+	}
+	return &hdr, nil
+}
+
+func newReader(r io.Reader) (archiveReader, error) {
+	rs := bufio.NewReader(r)
+	magic, err := rs.Peek(len(gzipMagic))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, gzipMagic) {
+		return ar.NewReader(rs)
+	}
+	z, err := gzip.NewReader(rs)
+	if err != nil {
+		return nil, err
+	}
+	return ipkReader{Reader: tar.NewReader(z)}, nil
+}
+
+func readDebian(r archiveReader) error {
 	h, err := r.Next()
 	if err != nil {
 		return err
@@ -28,7 +72,7 @@ func readDebian(r *ar.Reader) error {
 	return nil
 }
 
-func openFile(r *ar.Reader, file string) (*tar.Reader, error) {
+func openFile(r archiveReader, file string) (*tar.Reader, error) {
 	h, err := r.Next()
 	if err != nil {
 		return nil, err
@@ -0,0 +1,266 @@
+package deb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/pgp"
+)
+
+const (
+	DefaultSuite     = "stable"
+	DefaultComponent = "main"
+)
+
+const (
+	packagesFile  = "Packages"
+	releaseFile   = "Release"
+	inReleaseFile = "InRelease"
+	releaseSig    = "Release.gpg"
+	distsDir      = "dists"
+)
+
+type RepoConfig struct {
+	Suite     string
+	Component string
+	Origin    string
+	Label     string
+	Key       *pgp.Key
+}
+
+type repoEntry struct {
+	*PackageInfo
+	Control  []byte
+	Filename string
+	Length   int64
+	MD5      []byte
+	SHA256   []byte
+}
+
+type repoFile struct {
+	Name   string
+	Size   int
+	MD5    []byte
+	SHA256 []byte
+}
+
+func WriteRepository(dir string, config RepoConfig, when time.Time) error {
+	if config.Suite == "" {
+		config.Suite = DefaultSuite
+	}
+	if config.Component == "" {
+		config.Component = DefaultComponent
+	}
+	list, err := collectPackages(dir)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("%s: no deb packages found", dir)
+	}
+	var (
+		archs = getRepoArchs(list)
+		suite = filepath.Join(dir, distsDir, config.Suite)
+		files []repoFile
+	)
+	for _, a := range archs {
+		var buf bytes.Buffer
+		for _, e := range list {
+			if e.Arch != a && !isArchIndependent(e.Arch) {
+				continue
+			}
+			writePackageEntry(&buf, e)
+		}
+		base := filepath.Join(config.Component, "binary-"+a)
+		if err := os.MkdirAll(filepath.Join(suite, base), 0o755); err != nil {
+			return err
+		}
+		var (
+			plain = buf.Bytes()
+			zip   bytes.Buffer
+		)
+		z, _ := gzip.NewWriterLevel(&zip, gzip.BestCompression)
+		z.ModTime = when
+		if _, err := z.Write(plain); err != nil {
+			return err
+		}
+		if err := z.Close(); err != nil {
+			return err
+		}
+		for name, body := range map[string][]byte{packagesFile: plain, packagesFile + ".gz": zip.Bytes()} {
+			name = filepath.Join(base, name)
+			if err := packfile.WriteFile(filepath.Join(suite, name), body); err != nil {
+				return err
+			}
+			files = append(files, makeRepoFile(filepath.ToSlash(name), body))
+		}
+	}
+	slices.SortFunc(files, func(a, b repoFile) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	release := writeRelease(config, archs, files, when)
+	if config.Key != nil {
+		in, err := config.Key.Clearsign(release)
+		if err != nil {
+			return err
+		}
+		sig, err := config.Key.ArmoredSign(bytes.NewReader(release))
+		if err != nil {
+			return err
+		}
+		if err := packfile.WriteFile(filepath.Join(suite, inReleaseFile), in); err != nil {
+			return err
+		}
+		if err := packfile.WriteFile(filepath.Join(suite, releaseSig), sig); err != nil {
+			return err
+		}
+	}
+	return packfile.WriteFile(filepath.Join(suite, releaseFile), release)
+}
+
+func collectPackages(dir string) ([]*repoEntry, error) {
+	var list []*repoEntry
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(file) != ".deb" {
+			return nil
+		}
+		e, err := readRepoEntry(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if e.Filename, err = filepath.Rel(dir, file); err != nil {
+			return err
+		}
+		e.Filename = filepath.ToSlash(e.Filename)
+		list = append(list, e)
+		return nil
+	})
+	slices.SortFunc(list, func(a, b *repoEntry) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Filename, b.Filename)
+	})
+	return list, err
+}
+
+func readRepoEntry(file string) (*repoEntry, error) {
+	var (
+		e   repoEntry
+		err error
+	)
+	if e.Control, err = Control(file); err != nil {
+		return nil, err
+	}
+	if e.PackageInfo, err = parseControl(bytes.NewReader(e.Control)); err != nil {
+		return nil, err
+	}
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var (
+		md = md5.New()
+		sh = sha256.New()
+	)
+	if e.Length, err = io.Copy(io.MultiWriter(md, sh), r); err != nil {
+		return nil, err
+	}
+	e.MD5 = md.Sum(nil)
+	e.SHA256 = sh.Sum(nil)
+	return &e, nil
+}
+
+func writePackageEntry(w io.Writer, e *repoEntry) {
+	fmt.Fprintln(w, strings.TrimSpace(string(e.Control)))
+	fmt.Fprintf(w, "Filename: %s", e.Filename)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Size: %d", e.Length)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "MD5sum: %x", e.MD5)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "SHA256: %x", e.SHA256)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w)
+}
+
+func writeRelease(config RepoConfig, archs []string, files []repoFile, when time.Time) []byte {
+	var buf bytes.Buffer
+	if config.Origin != "" {
+		fmt.Fprintf(&buf, "Origin: %s", config.Origin)
+		fmt.Fprintln(&buf)
+	}
+	if config.Label != "" {
+		fmt.Fprintf(&buf, "Label: %s", config.Label)
+		fmt.Fprintln(&buf)
+	}
+	fmt.Fprintf(&buf, "Suite: %s", config.Suite)
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "Codename: %s", config.Suite)
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "Date: %s", when.UTC().Format(time.RFC1123))
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "Architectures: %s", strings.Join(archs, " "))
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "Components: %s", config.Component)
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "MD5Sum:")
+	for _, f := range files {
+		fmt.Fprintf(&buf, " %x %16d %s", f.MD5, f.Size, f.Name)
+		fmt.Fprintln(&buf)
+	}
+	fmt.Fprintln(&buf, "SHA256:")
+	for _, f := range files {
+		fmt.Fprintf(&buf, " %x %16d %s", f.SHA256, f.Size, f.Name)
+		fmt.Fprintln(&buf)
+	}
+	return buf.Bytes()
+}
+
+func makeRepoFile(name string, body []byte) repoFile {
+	var (
+		md = md5.Sum(body)
+		sh = sha256.Sum256(body)
+	)
+	return repoFile{
+		Name:   name,
+		Size:   len(body),
+		MD5:    md[:],
+		SHA256: sh[:],
+	}
+}
+
+func getRepoArchs(list []*repoEntry) []string {
+	var archs []string
+	for _, e := range list {
+		if isArchIndependent(e.Arch) || slices.Contains(archs, e.Arch) {
+			continue
+		}
+		archs = append(archs, e.Arch)
+	}
+	if len(archs) == 0 {
+		archs = append(archs, packfile.ArchAll)
+	}
+	slices.Sort(archs)
+	return archs
+}
+
+func isArchIndependent(arch string) bool {
+	return arch == packfile.ArchAll || arch == packfile.ArchNo
+}
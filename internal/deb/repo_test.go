@@ -0,0 +1,39 @@
+package deb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWritePackageEntry(t *testing.T) {
+	tests := []struct {
+		entry repoEntry
+		want  string
+	}{
+		{
+			entry: repoEntry{
+				Control:  []byte("Package: foo\nVersion: 1.0\n"),
+				Filename: "pool/main/f/foo/foo_1.0_amd64.deb",
+				Length:   1024,
+				MD5:      []byte{0x01, 0x02},
+				SHA256:   []byte{0xab, 0xcd},
+			},
+			want: "Package: foo\nVersion: 1.0\nFilename: pool/main/f/foo/foo_1.0_amd64.deb\nSize: 1024\nMD5sum: 0102\nSHA256: abcd\n\n",
+		},
+		{
+			entry: repoEntry{
+				Control:  []byte("Package: bar\nDescription: short\n long\n .\n more\n\n\n"),
+				Filename: "bar.deb",
+				Length:   0,
+			},
+			want: "Package: bar\nDescription: short\n long\n .\n more\nFilename: bar.deb\nSize: 0\nMD5sum: \nSHA256: \n\n",
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		writePackageEntry(&buf, &tt.entry)
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: want %q, got %q", tt.entry.Filename, tt.want, got)
+		}
+	}
+}
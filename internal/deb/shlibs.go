@@ -0,0 +1,119 @@
+package deb
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+const dpkgInfoDir = "/var/lib/dpkg/info"
+
+var sonameVersion = regexp.MustCompile(`^(.+)-([0-9][^-]*)\.so$`)
+
+type shlibs struct {
+	libs    map[string][]packfile.Dependency
+	symbols map[string][]packfile.Dependency
+}
+
+func ResolveLibs(files, libs []string) ([]packfile.Dependency, error) {
+	files = slices.Clone(files)
+	for _, ext := range []string{"*.shlibs", "*.symbols"} {
+		list, _ := filepath.Glob(filepath.Join(dpkgInfoDir, ext))
+		files = append(files, list...)
+	}
+	db := shlibs{
+		libs:    make(map[string][]packfile.Dependency),
+		symbols: make(map[string][]packfile.Dependency),
+	}
+	for _, f := range files {
+		if err := db.load(f); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		list []packfile.Dependency
+		seen = make(map[string]struct{})
+	)
+	for _, lib := range libs {
+		deps, ok := db.resolve(lib)
+		if !ok {
+			return nil, fmt.Errorf("%s: no package found providing library", lib)
+		}
+		for _, d := range deps {
+			if _, ok := seen[d.Package]; ok {
+				continue
+			}
+			seen[d.Package] = struct{}{}
+			list = append(list, d)
+		}
+	}
+	return list, nil
+}
+
+func (s shlibs) resolve(soname string) ([]packfile.Dependency, bool) {
+	name, version := splitSoname(soname)
+	if deps, ok := s.libs[name+" "+version]; ok {
+		return deps, ok
+	}
+	deps, ok := s.symbols[soname]
+	return deps, ok
+}
+
+func (s shlibs) load(file string) error {
+	r, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := scan.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch line[0] {
+		case ' ', '\t', '|', '*':
+			continue
+		default:
+		}
+		fields := strings.Fields(line)
+		if strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		if strings.Contains(fields[0], ".so") {
+			if len(fields) < 2 {
+				continue
+			}
+			soname := fields[0]
+			if _, ok := s.symbols[soname]; !ok {
+				s.symbols[soname] = parseDependencies(fields[1], "depends")
+			}
+			continue
+		}
+		if len(fields) < 3 {
+			continue
+		}
+		key := fields[0] + " " + fields[1]
+		if _, ok := s.libs[key]; !ok {
+			s.libs[key] = parseDependencies(strings.Join(fields[2:], " "), "depends")
+		}
+	}
+	return scan.Err()
+}
+
+func splitSoname(soname string) (string, string) {
+	if name, version, ok := strings.Cut(soname, ".so."); ok {
+		return name, version
+	}
+	if m := sonameVersion.FindStringSubmatch(soname); m != nil {
+		return m[1], m[2]
+	}
+	return strings.TrimSuffix(soname, ".so"), ""
+}
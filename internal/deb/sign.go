@@ -0,0 +1,264 @@
+package deb
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/midbel/packit/internal/pgp"
+	"github.com/midbel/tape"
+)
+
+const (
+	RoleOrigin  = "origin"
+	RoleBuilder = "builder"
+	signPrefix  = "_gpg"
+)
+
+func (d *DebBuilder) SignWith(file, role string) error {
+	switch role {
+	case "":
+		role = RoleOrigin
+	case RoleOrigin, RoleBuilder:
+	default:
+		return fmt.Errorf("%s: signing role not supported", role)
+	}
+	key, err := pgp.ReadPrivateKey(file)
+	if err != nil {
+		return err
+	}
+	d.key = key
+	d.role = role
+	return nil
+}
+
+func (d DebBuilder) writeSignature(ctrl, data *os.File) error {
+	for _, f := range []*os.File{ctrl, data} {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	var (
+		body []byte
+		err  error
+	)
+	if d.role == RoleOrigin {
+		body, err = d.key.Sign(io.MultiReader(strings.NewReader(debVersion), ctrl, data))
+	} else {
+		body, err = d.signFiles(ctrl, data)
+	}
+	if err != nil {
+		return err
+	}
+	h := tape.Header{
+		Filename: signPrefix + d.role,
+		Uid:      0,
+		Gid:      0,
+		Mode:     0644,
+		Size:     int64(len(body)),
+		ModTime:  d.buildTime,
+	}
+	if err := d.writer.WriteHeader(&h); err != nil {
+		return err
+	}
+	_, err = d.writer.Write(body)
+	return err
+}
+
+func (d DebBuilder) signFiles(ctrl, data *os.File) ([]byte, error) {
+	var str bytes.Buffer
+	fmt.Fprintln(&str, "Version: 4")
+	fmt.Fprintf(&str, "Signer: %s", d.key.Identity())
+	fmt.Fprintln(&str)
+	fmt.Fprintf(&str, "Date: %s", d.buildTime.UTC().Format("Mon Jan _2 15:04:05 2006"))
+	fmt.Fprintln(&str)
+	fmt.Fprintf(&str, "Role: %s", d.role)
+	fmt.Fprintln(&str)
+	fmt.Fprintln(&str, "Files: ")
+
+	files := []struct {
+		Name string
+		io.Reader
+	}{
+		{Name: debianFile, Reader: strings.NewReader(debVersion)},
+		{Name: ControlFile, Reader: ctrl},
+		{Name: DataFile, Reader: data},
+	}
+	for _, f := range files {
+		var (
+			md = md5.New()
+			sh = sha1.New()
+		)
+		n, err := io.Copy(io.MultiWriter(md, sh), f)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&str, "\t%x %x %d %s", md.Sum(nil), sh.Sum(nil), n, f.Name)
+		fmt.Fprintln(&str)
+	}
+	return d.key.Clearsign(str.Bytes())
+}
+
+type memberSum struct {
+	MD5  string
+	SHA1 string
+	Size int64
+}
+
+func checkSignature(file string, keyring *pgp.Keyring) error {
+	name, sig, err := readSignature(file)
+	if err != nil {
+		return err
+	}
+	var (
+		verifier *pgp.Verifier
+		files    map[string]memberSum
+	)
+	if name == signPrefix+RoleOrigin {
+		if verifier, err = pgp.NewVerifier(sig); err != nil {
+			return err
+		}
+	} else {
+		text, err := keyring.VerifyClearsign(sig)
+		if err != nil {
+			return err
+		}
+		if files, err = readSignedFiles(text); err != nil {
+			return err
+		}
+	}
+
+	r, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	rs, err := newReader(r)
+	if err != nil {
+		return err
+	}
+	for {
+		h, err := rs.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		if strings.HasPrefix(h.Filename, "_") {
+			if _, err := io.Copy(io.Discard, io.LimitReader(rs, h.Size)); err != nil {
+				return err
+			}
+			continue
+		}
+		var (
+			md = md5.New()
+			sh = sha1.New()
+			ws = io.MultiWriter(md, sh)
+		)
+		if verifier != nil {
+			ws = io.MultiWriter(ws, verifier)
+		}
+		n, err := io.Copy(ws, io.LimitReader(rs, h.Size))
+		if err != nil {
+			return err
+		}
+		if files == nil {
+			continue
+		}
+		want, ok := files[h.Filename]
+		if !ok {
+			return fmt.Errorf("%s: file not signed", h.Filename)
+		}
+		delete(files, h.Filename)
+		if want.Size != n || want.MD5 != hex.EncodeToString(md.Sum(nil)) || want.SHA1 != hex.EncodeToString(sh.Sum(nil)) {
+			return fmt.Errorf("%s: checksum mismatched", h.Filename)
+		}
+	}
+	for name := range files {
+		return fmt.Errorf("%s: file signed but not in package", name)
+	}
+	if verifier != nil {
+		return verifier.Verify(keyring)
+	}
+	return nil
+}
+
+func readSignature(file string) (string, []byte, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+
+	rs, err := newReader(r)
+	if err != nil {
+		return "", nil, err
+	}
+	var (
+		name string
+		sig  []byte
+	)
+	for {
+		h, err := rs.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return "", nil, err
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, io.LimitReader(rs, h.Size)); err != nil {
+			return "", nil, err
+		}
+		if !strings.HasPrefix(h.Filename, signPrefix) || (sig != nil && name == signPrefix+RoleOrigin) {
+			continue
+		}
+		name, sig = h.Filename, buf.Bytes()
+	}
+	if sig == nil {
+		return "", nil, fmt.Errorf("signature: package is not signed")
+	}
+	return name, sig, nil
+}
+
+func readSignedFiles(text []byte) (map[string]memberSum, error) {
+	var (
+		scan  = bufio.NewScanner(bytes.NewReader(text))
+		list  = make(map[string]memberSum)
+		files bool
+	)
+	for scan.Scan() {
+		line := scan.Text()
+		if !files {
+			files = strings.HasPrefix(line, "Files:")
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		list[fields[3]] = memberSum{
+			MD5:  fields[0],
+			SHA1: fields[1],
+			Size: size,
+		}
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("signature: no files found in signed message")
+	}
+	return list, scan.Err()
+}
@@ -12,17 +12,17 @@ import (
 	"os"
 	"strings"
 
-	"github.com/midbel/tape/ar"
+	"github.com/midbel/packit/internal/pgp"
 )
 
-func Check(file string) error {
+func Check(file string, keyring *pgp.Keyring) error {
 	r, err := os.Open(file)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
 
-	rs, err := ar.NewReader(r)
+	rs, err := newReader(r)
 	if err != nil {
 		return err
 	}
@@ -37,10 +37,13 @@ func Check(file string) error {
 	if err != nil {
 		return err
 	}
-	return checkFiles(rs, sums)
+	if err := checkFiles(rs, sums); err != nil || keyring == nil {
+		return err
+	}
+	return checkSignature(file, keyring)
 }
 
-func checkFiles(r *ar.Reader, sums map[string]string) error {
+func checkFiles(r archiveReader, sums map[string]string) error {
 	rs, err := openFile(r, DataFile)
 	if err != nil {
 		return err
@@ -56,7 +59,7 @@ func checkFiles(r *ar.Reader, sums map[string]string) error {
 		if h.Typeflag != tar.TypeReg {
 			continue
 		}
-		value, ok := sums[h.Name]
+		value, ok := sums[strings.TrimPrefix(h.Name, "./")]
 		if !ok {
 			return fmt.Errorf("%s: file in %s but not in %s", h.Name, DataFile, md5File)
 		}
@@ -90,7 +93,7 @@ func readChecksums(r io.Reader) (map[string]string, error) {
 	return list, nil
 }
 
-func readControl(r *ar.Reader, file string) (io.Reader, error) {
+func readControl(r archiveReader, file string) (io.Reader, error) {
 	rs, err := openFile(r, ControlFile)
 	if err != nil {
 		return nil, err
@@ -103,7 +106,7 @@ func readControl(r *ar.Reader, file string) (io.Reader, error) {
 			}
 			return nil, err
 		}
-		if h.Name == file {
+		if strings.TrimPrefix(h.Name, "./") == file {
 			var tmp bytes.Buffer
 			if _, err := io.Copy(&tmp, io.LimitReader(rs, h.Size)); err != nil {
 				return nil, err
@@ -0,0 +1,129 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"slices"
+)
+
+const (
+	shtProgbits = 0x1
+	shtNote     = 0x7
+	ntBuildID   = 0x3
+)
+
+const debugLinkSection = ".gnu_debuglink"
+
+func (f *File) BuildID() string {
+	for _, sh := range f.Sections {
+		if sh.Type != shtNote || sh.Label != ".note.gnu.build-id" {
+			continue
+		}
+		buf, err := readSection(f.reader, sh)
+		if err != nil || len(buf) < 12 {
+			return ""
+		}
+		var (
+			order  = f.ByteOrder()
+			namesz = order.Uint32(buf)
+			descsz = order.Uint32(buf[4:])
+			kind   = order.Uint32(buf[8:])
+			offset = 12 + (namesz+3)&^3
+		)
+		if kind != ntBuildID || uint64(offset+descsz) > uint64(len(buf)) {
+			return ""
+		}
+		return hex.EncodeToString(buf[offset : offset+descsz])
+	}
+	return ""
+}
+
+func (f *File) StripWithLink(w io.Writer, name string, crc uint32) error {
+	if slices.ContainsFunc(f.Sections, func(sh SectionHeader) bool {
+		return sh.Label == debugLinkSection
+	}) {
+		return ErrStrip
+	}
+	data := append([]byte(name), 0)
+	for len(data)%4 != 0 {
+		data = append(data, 0)
+	}
+	sum := make([]byte, 4)
+	f.ByteOrder().PutUint32(sum, crc)
+	data = append(data, sum...)
+
+	link := section{
+		SectionHeader: SectionHeader{
+			Label:     debugLinkSection,
+			Type:      shtProgbits,
+			Size:      uint64(len(data)),
+			AddrAlign: 4,
+		},
+		Data: data,
+	}
+	return f.strip(w, []section{link})
+}
+
+func (f *File) KeepDebug(w io.Writer) error {
+	if f.Stripped() {
+		return ErrStrip
+	}
+	header := make([]byte, f.Size)
+	if _, err := f.reader.ReadAt(header, 0); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	out := bytes.NewBuffer(header)
+
+	list := make([]section, 0, len(f.Sections))
+	for _, sh := range f.Sections {
+		list = append(list, section{SectionHeader: sh})
+	}
+	for i, s := range list {
+		if s.isNull() {
+			continue
+		}
+		if !s.canStrip() && !s.isName() && s.Type != shtNote {
+			list[i].Type = shtNobits
+			list[i].Offset = uint64(out.Len())
+			continue
+		}
+		if s.isEmpty() {
+			list[i].Offset = uint64(out.Len())
+			continue
+		}
+		data, err := readSection(f.reader, s.SectionHeader)
+		if err != nil {
+			return err
+		}
+		pad(out, s.AddrAlign)
+		list[i].Offset = uint64(out.Len())
+		out.Write(data)
+	}
+	if f.Is32() {
+		pad(out, 4)
+	} else {
+		pad(out, 8)
+	}
+	offset := out.Len()
+	if err := writeSectionHeaders(f, list, out); err != nil {
+		return err
+	}
+
+	var (
+		body  = out.Bytes()
+		order = f.ByteOrder()
+	)
+	if f.Is32() {
+		order.PutUint32(body[0x1c:], 0)
+		order.PutUint32(body[0x20:], uint32(offset))
+		order.PutUint16(body[0x2c:], 0)
+	} else {
+		order.PutUint64(body[0x20:], 0)
+		order.PutUint64(body[0x28:], uint64(offset))
+		order.PutUint16(body[0x38:], 0)
+	}
+	_, err := w.Write(body)
+	return err
+}
@@ -0,0 +1,475 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+)
+
+const (
+	Arch32 = 1
+	Arch64 = 2
+)
+
+const (
+	dtNull   = 0
+	dtNeeded = 1
+	dtSoname = 14
+)
+
+var magic = []byte{0x7F, 0x45, 0x4c, 0x46}
+
+type File struct {
+	*ELFHeader
+	reader io.ReaderAt
+	closer io.Closer
+}
+
+func IsElf(r io.ReaderAt) bool {
+	buf := make([]byte, len(magic))
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		return false
+	}
+	return bytes.Equal(buf, magic)
+}
+
+func Open(file string) (*File, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	elf, err := NewFile(r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	elf.closer = r
+	return elf, nil
+}
+
+func NewFile(r io.ReaderAt) (*File, error) {
+	hdr, err := load(r)
+	if err != nil {
+		return nil, err
+	}
+	elf := File{
+		ELFHeader: hdr,
+		reader:    r,
+	}
+	return &elf, nil
+}
+
+func (f *File) Names() []string {
+	var list []string
+	for _, sh := range f.Sections {
+		list = append(list, sh.Label)
+	}
+	return list
+}
+
+func (f *File) Static() bool {
+	ix := slices.IndexFunc(f.Sections, func(sh SectionHeader) bool {
+		return sh.Label == ".dynamic" || sh.Label == ".interp"
+	})
+	return ix < 0
+}
+
+func (f *File) Linker() string {
+	name, _ := f.getInterp()
+	return name
+}
+
+func (f *File) Libs() []string {
+	entries, err := f.getDynEntries()
+	if err != nil {
+		return nil
+	}
+	return f.getDynStrings(entries, dtNeeded)
+}
+
+func (f *File) Soname() string {
+	entries, err := f.getDynEntries()
+	if err != nil {
+		return ""
+	}
+	names := f.getDynStrings(entries, dtSoname)
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+func (f *File) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
+
+func (f *File) getDynStrings(entries []DynamicEntry, tag uint64) []string {
+	ix := slices.IndexFunc(f.Sections, func(h SectionHeader) bool {
+		return h.Label == ".dynstr"
+	})
+	if ix < 0 {
+		return nil
+	}
+	buf, err := readSection(f.reader, f.Sections[ix])
+	if err != nil {
+		return nil
+	}
+	var list []string
+	for _, e := range entries {
+		if e.Tag != tag || e.Value >= uint64(len(buf)) {
+			continue
+		}
+		str := buf[e.Value:]
+		if x := bytes.IndexByte(str, 0); x >= 0 {
+			str = str[:x]
+		}
+		list = append(list, string(str))
+	}
+	return list
+}
+
+func (f *File) getInterp() (string, error) {
+	ix := slices.IndexFunc(f.Sections, func(sh SectionHeader) bool {
+		return sh.Label == ".interp"
+	})
+	if ix < 0 {
+		return "", fmt.Errorf(".interp section not found")
+	}
+	buf, err := readSection(f.reader, f.Sections[ix])
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimRight(buf, "\x00")), nil
+}
+
+func (f *File) getDynEntries() ([]DynamicEntry, error) {
+	ix := slices.IndexFunc(f.Sections, func(sh SectionHeader) bool {
+		return sh.Label == ".dynamic"
+	})
+	if ix < 0 {
+		return nil, fmt.Errorf("dynamic section not found")
+	}
+	var (
+		sh   = f.Sections[ix]
+		rs   = io.NewSectionReader(f.reader, int64(sh.Offset), int64(sh.Size))
+		list []DynamicEntry
+	)
+	for {
+		var (
+			e   DynamicEntry
+			err error
+		)
+		if f.Is32() {
+			var tag, value uint32
+			if err = binary.Read(rs, f.ByteOrder(), &tag); err == nil {
+				err = binary.Read(rs, f.ByteOrder(), &value)
+			}
+			e.Tag, e.Value = uint64(tag), uint64(value)
+		} else {
+			if err = binary.Read(rs, f.ByteOrder(), &e.Tag); err == nil {
+				err = binary.Read(rs, f.ByteOrder(), &e.Value)
+			}
+		}
+		if err != nil || e.Tag == dtNull {
+			break
+		}
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+type ELFHeader struct {
+	Class       uint8
+	Endianness  uint8
+	Version     uint8
+	AbiOs       uint8
+	AbiVersion  uint8
+	Type        uint16
+	Machine     uint16
+	EntryAddr   uint64
+	ProgramAddr uint64
+	SectionAddr uint64
+	ElfVersion  uint32
+	Flags       uint32
+	Size        uint16
+	PhSize      uint16
+	PhCount     uint16
+	ShSize      uint16
+	ShCount     uint16
+	NamesIndex  uint16
+
+	Programs []ProgramHeader
+	Sections []SectionHeader
+}
+
+func (e ELFHeader) Is32() bool {
+	return e.Class == Arch32
+}
+
+func (e ELFHeader) Is64() bool {
+	return e.Class == Arch64
+}
+
+func (e ELFHeader) ByteOrder() binary.ByteOrder {
+	if e.Endianness == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+type DynamicEntry struct {
+	Tag   uint64
+	Value uint64
+}
+
+type ProgramHeader struct {
+	Type         uint32
+	Flags        uint32
+	Offset       uint64
+	VirtualAddr  uint64
+	PhysicalAddr uint64
+	FileSize     uint64
+	MemSize      uint64
+	Align        uint64
+}
+
+type SectionHeader struct {
+	Label     string
+	Name      uint32
+	Type      uint32
+	Flags     uint64
+	Addr      uint64
+	Offset    uint64
+	Size      uint64
+	Link      uint32
+	Info      uint32
+	AddrAlign uint64
+	EntSize   uint64
+}
+
+func (s SectionHeader) StartAt() uint64 {
+	return s.Offset
+}
+
+func (s SectionHeader) EndAt() uint64 {
+	return s.Offset + s.Size
+}
+
+func setLabels(elf *ELFHeader, r io.ReaderAt) error {
+	if int(elf.NamesIndex) >= len(elf.Sections) {
+		return fmt.Errorf("section names index out of range")
+	}
+	ns := elf.Sections[elf.NamesIndex]
+	buf, err := readSection(r, ns)
+	if err != nil {
+		return err
+	}
+	for i, sh := range elf.Sections {
+		if uint64(sh.Name) >= ns.Size {
+			continue
+		}
+		ix := bytes.IndexByte(buf[sh.Name:], 0)
+		if ix < 0 {
+			continue
+		}
+		elf.Sections[i].Label = string(buf[sh.Name : int(sh.Name)+ix])
+	}
+	return nil
+}
+
+func readSection(r io.ReaderAt, sh SectionHeader) ([]byte, error) {
+	if int64(sh.Offset) < 0 || int64(sh.Size) < 0 {
+		return nil, fmt.Errorf("section out of range")
+	}
+	buf, err := io.ReadAll(io.NewSectionReader(r, int64(sh.Offset), int64(sh.Size)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(buf)) != sh.Size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf, nil
+}
+
+func load(r io.ReaderAt) (*ELFHeader, error) {
+	elf, err := readHeader(io.NewSectionReader(r, 0, math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+	rs := io.NewSectionReader(r, int64(elf.ProgramAddr), math.MaxInt64-int64(elf.ProgramAddr))
+	for i := 0; i < int(elf.PhCount); i++ {
+		if err := readProgramHeader(elf, rs); err != nil {
+			return nil, err
+		}
+	}
+	rs = io.NewSectionReader(r, int64(elf.SectionAddr), math.MaxInt64-int64(elf.SectionAddr))
+	for i := 0; i < int(elf.ShCount); i++ {
+		if err := readSectionHeader(elf, rs); err != nil {
+			return nil, err
+		}
+	}
+	return elf, setLabels(elf, r)
+}
+
+func readSectionHeader(elf *ELFHeader, r io.Reader) error {
+	var sh SectionHeader
+
+	binary.Read(r, elf.ByteOrder(), &sh.Name)
+	binary.Read(r, elf.ByteOrder(), &sh.Type)
+
+	if elf.Is32() {
+		var (
+			flags     uint32
+			addr      uint32
+			offset    uint32
+			size      uint32
+			link      uint32
+			info      uint32
+			addrAlign uint32
+			entSize   uint32
+		)
+		binary.Read(r, elf.ByteOrder(), &flags)
+		binary.Read(r, elf.ByteOrder(), &addr)
+		binary.Read(r, elf.ByteOrder(), &offset)
+		binary.Read(r, elf.ByteOrder(), &size)
+		binary.Read(r, elf.ByteOrder(), &link)
+		binary.Read(r, elf.ByteOrder(), &info)
+		binary.Read(r, elf.ByteOrder(), &addrAlign)
+		if err := binary.Read(r, elf.ByteOrder(), &entSize); err != nil {
+			return err
+		}
+
+		sh.Flags = uint64(flags)
+		sh.Addr = uint64(addr)
+		sh.Offset = uint64(offset)
+		sh.Size = uint64(size)
+		sh.Link = link
+		sh.Info = info
+		sh.AddrAlign = uint64(addrAlign)
+		sh.EntSize = uint64(entSize)
+	} else {
+		binary.Read(r, elf.ByteOrder(), &sh.Flags)
+		binary.Read(r, elf.ByteOrder(), &sh.Addr)
+		binary.Read(r, elf.ByteOrder(), &sh.Offset)
+		binary.Read(r, elf.ByteOrder(), &sh.Size)
+		binary.Read(r, elf.ByteOrder(), &sh.Link)
+		binary.Read(r, elf.ByteOrder(), &sh.Info)
+		binary.Read(r, elf.ByteOrder(), &sh.AddrAlign)
+		if err := binary.Read(r, elf.ByteOrder(), &sh.EntSize); err != nil {
+			return err
+		}
+	}
+	elf.Sections = append(elf.Sections, sh)
+	return nil
+}
+
+func readProgramHeader(elf *ELFHeader, r io.Reader) error {
+	var ph ProgramHeader
+	binary.Read(r, elf.ByteOrder(), &ph.Type)
+	if elf.Is32() {
+		var (
+			offset       uint32
+			virtualAddr  uint32
+			physicalAddr uint32
+			sizeFile     uint32
+			sizeMem      uint32
+			flags        uint32
+			align        uint32
+		)
+		binary.Read(r, elf.ByteOrder(), &offset)
+		binary.Read(r, elf.ByteOrder(), &virtualAddr)
+		binary.Read(r, elf.ByteOrder(), &physicalAddr)
+		binary.Read(r, elf.ByteOrder(), &sizeFile)
+		binary.Read(r, elf.ByteOrder(), &sizeMem)
+		binary.Read(r, elf.ByteOrder(), &flags)
+		if err := binary.Read(r, elf.ByteOrder(), &align); err != nil {
+			return err
+		}
+
+		ph.Offset = uint64(offset)
+		ph.VirtualAddr = uint64(virtualAddr)
+		ph.PhysicalAddr = uint64(physicalAddr)
+		ph.FileSize = uint64(sizeFile)
+		ph.MemSize = uint64(sizeMem)
+		ph.Align = uint64(align)
+		ph.Flags = flags
+	} else {
+		binary.Read(r, elf.ByteOrder(), &ph.Flags)
+		binary.Read(r, elf.ByteOrder(), &ph.Offset)
+		binary.Read(r, elf.ByteOrder(), &ph.VirtualAddr)
+		binary.Read(r, elf.ByteOrder(), &ph.PhysicalAddr)
+		binary.Read(r, elf.ByteOrder(), &ph.FileSize)
+		binary.Read(r, elf.ByteOrder(), &ph.MemSize)
+		if err := binary.Read(r, elf.ByteOrder(), &ph.Align); err != nil {
+			return err
+		}
+	}
+	elf.Programs = append(elf.Programs, ph)
+	return nil
+}
+
+func readHeader(rs io.Reader) (*ELFHeader, error) {
+	var (
+		elf ELFHeader
+		err error
+		buf = make([]byte, 4)
+	)
+
+	if _, err = io.ReadFull(rs, buf); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(buf, magic) {
+		return nil, fmt.Errorf("invalid magic %x", buf)
+	}
+
+	binary.Read(rs, binary.BigEndian, &elf.Class)
+	binary.Read(rs, binary.BigEndian, &elf.Endianness)
+	binary.Read(rs, binary.BigEndian, &elf.Version)
+	binary.Read(rs, binary.BigEndian, &elf.AbiOs)
+	binary.Read(rs, binary.BigEndian, &elf.AbiVersion)
+
+	if _, err = io.CopyN(io.Discard, rs, 7); err != nil {
+		return nil, err
+	}
+
+	binary.Read(rs, elf.ByteOrder(), &elf.Type)
+	binary.Read(rs, elf.ByteOrder(), &elf.Machine)
+	binary.Read(rs, elf.ByteOrder(), &elf.ElfVersion)
+	if elf.Is32() {
+		var (
+			entAddr  uint32
+			progAddr uint32
+			sectAddr uint32
+		)
+		binary.Read(rs, elf.ByteOrder(), &entAddr)
+		binary.Read(rs, elf.ByteOrder(), &progAddr)
+		binary.Read(rs, elf.ByteOrder(), &sectAddr)
+
+		elf.EntryAddr = uint64(entAddr)
+		elf.ProgramAddr = uint64(progAddr)
+		elf.SectionAddr = uint64(sectAddr)
+	} else {
+		binary.Read(rs, elf.ByteOrder(), &elf.EntryAddr)
+		binary.Read(rs, elf.ByteOrder(), &elf.ProgramAddr)
+		binary.Read(rs, elf.ByteOrder(), &elf.SectionAddr)
+	}
+
+	binary.Read(rs, elf.ByteOrder(), &elf.Flags)
+	binary.Read(rs, elf.ByteOrder(), &elf.Size)
+	binary.Read(rs, elf.ByteOrder(), &elf.PhSize)
+	binary.Read(rs, elf.ByteOrder(), &elf.PhCount)
+	binary.Read(rs, elf.ByteOrder(), &elf.ShSize)
+	binary.Read(rs, elf.ByteOrder(), &elf.ShCount)
+	if err := binary.Read(rs, elf.ByteOrder(), &elf.NamesIndex); err != nil {
+		return nil, err
+	}
+
+	return &elf, nil
+}
@@ -0,0 +1,168 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLibs(t *testing.T) {
+	tests := []struct {
+		name   string
+		needed []string
+		soname string
+		interp string
+		update func([]SectionHeader)
+		libs   []string
+		err    bool
+	}{
+		{
+			name:   "executable",
+			needed: []string{"libm.so.6", "libc.so.6"},
+			interp: "/lib64/ld-linux-x86-64.so.2",
+			libs:   []string{"libm.so.6", "libc.so.6"},
+		},
+		{
+			name:   "library",
+			needed: []string{"libc.so.6"},
+			soname: "libfoo.so.1",
+			libs:   []string{"libc.so.6"},
+		},
+		{
+			name: "no-needed",
+		},
+		{
+			name:   "dynstr-too-large",
+			needed: []string{"libc.so.6"},
+			update: func(list []SectionHeader) {
+				list[1].Size = math.MaxInt64
+			},
+		},
+		{
+			name:   "dynstr-out-of-file",
+			needed: []string{"libc.so.6"},
+			update: func(list []SectionHeader) {
+				list[1].Offset = 1 << 40
+			},
+		},
+		{
+			name:   "names-too-large",
+			needed: []string{"libc.so.6"},
+			update: func(list []SectionHeader) {
+				list[len(list)-1].Size = math.MaxUint64
+			},
+			err: true,
+		},
+	}
+	for _, tt := range tests {
+		buf := makeElf(tt.needed, tt.soname, tt.interp, tt.update)
+		f, err := NewFile(bytes.NewReader(buf))
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.name, err)
+			continue
+		}
+		if got := f.Libs(); !reflect.DeepEqual(got, tt.libs) {
+			t.Errorf("%s: want libs %v, got %v", tt.name, tt.libs, got)
+		}
+		if tt.update != nil {
+			continue
+		}
+		if got := f.Soname(); got != tt.soname {
+			t.Errorf("%s: want soname %q, got %q", tt.name, tt.soname, got)
+		}
+		if got := f.Linker(); got != tt.interp {
+			t.Errorf("%s: want linker %q, got %q", tt.name, tt.interp, got)
+		}
+	}
+}
+
+func makeElf(needed []string, soname, interp string, update func([]SectionHeader)) []byte {
+	const (
+		headerSize  = 64
+		sectionSize = 64
+	)
+	var (
+		order   = binary.LittleEndian
+		data    bytes.Buffer
+		dynstr  = []byte{0}
+		dynamic bytes.Buffer
+		names   = []byte{0}
+		list    = []SectionHeader{{}}
+	)
+	addString := func(str string) uint64 {
+		ix := len(dynstr)
+		dynstr = append(append(dynstr, str...), 0)
+		return uint64(ix)
+	}
+	addSection := func(label string, kind uint32, body []byte) {
+		sh := SectionHeader{
+			Name:   uint32(len(names)),
+			Type:   kind,
+			Offset: uint64(headerSize + data.Len()),
+			Size:   uint64(len(body)),
+		}
+		names = append(append(names, label...), 0)
+		data.Write(body)
+		list = append(list, sh)
+	}
+	for _, n := range needed {
+		binary.Write(&dynamic, order, []uint64{dtNeeded, addString(n)})
+	}
+	if soname != "" {
+		binary.Write(&dynamic, order, []uint64{dtSoname, addString(soname)})
+	}
+	binary.Write(&dynamic, order, []uint64{dtNull, 0})
+
+	addSection(".dynstr", 3, dynstr)
+	addSection(".dynamic", 6, dynamic.Bytes())
+	if interp != "" {
+		addSection(".interp", 1, append([]byte(interp), 0))
+	}
+	shstr := uint32(len(names))
+	names = append(append(names, ".shstrtab"...), 0)
+	list = append(list, SectionHeader{
+		Name:   shstr,
+		Type:   3,
+		Offset: uint64(headerSize + data.Len()),
+		Size:   uint64(len(names)),
+	})
+	data.Write(names)
+
+	if update != nil {
+		update(list)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(magic)
+	buf.Write([]byte{Arch64, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	binary.Write(&buf, order, uint16(3))
+	binary.Write(&buf, order, uint16(62))
+	binary.Write(&buf, order, uint32(1))
+	binary.Write(&buf, order, uint64(0))
+	binary.Write(&buf, order, uint64(0))
+	binary.Write(&buf, order, uint64(headerSize+data.Len()))
+	binary.Write(&buf, order, uint32(0))
+	binary.Write(&buf, order, uint16(headerSize))
+	binary.Write(&buf, order, uint16(56))
+	binary.Write(&buf, order, uint16(0))
+	binary.Write(&buf, order, uint16(sectionSize))
+	binary.Write(&buf, order, uint16(len(list)))
+	binary.Write(&buf, order, uint16(len(list)-1))
+	buf.Write(data.Bytes())
+	for _, sh := range list {
+		binary.Write(&buf, order, sh.Name)
+		binary.Write(&buf, order, sh.Type)
+		binary.Write(&buf, order, []uint64{sh.Flags, sh.Addr, sh.Offset, sh.Size})
+		binary.Write(&buf, order, []uint32{sh.Link, sh.Info})
+		binary.Write(&buf, order, []uint64{sh.AddrAlign, sh.EntSize})
+	}
+	return buf.Bytes()
+}
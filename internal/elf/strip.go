@@ -0,0 +1,241 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+)
+
+var ErrStrip = errors.New("file can not be stripped")
+
+const (
+	shtNull     = 0x0
+	shtRela     = 0x4
+	shtNobits   = 0x8
+	shtRel      = 0x9
+	shfAlloc    = 0x2
+	shfInfoLink = 0x40
+)
+
+type section struct {
+	SectionHeader
+	Data []byte
+}
+
+func (f *File) Stripped() bool {
+	return !slices.ContainsFunc(f.Sections, func(sh SectionHeader) bool {
+		return sh.canStrip()
+	})
+}
+
+func (f *File) Strip(w io.Writer) error {
+	return f.strip(w, nil)
+}
+
+func (f *File) strip(w io.Writer, extra []section) error {
+	if f.Stripped() {
+		return ErrStrip
+	}
+	var (
+		kept  []int
+		index = make(map[uint32]uint32)
+	)
+	for i, sh := range f.Sections {
+		if !sh.canStrip() {
+			index[uint32(i)] = uint32(len(kept))
+			kept = append(kept, i)
+			continue
+		}
+		if sh.Flags&shfAlloc != 0 {
+			return ErrStrip
+		}
+	}
+	list := make([]section, 0, len(kept)+len(extra))
+	for _, i := range kept {
+		sh := f.Sections[i]
+		if sh.Link != 0 {
+			link, ok := index[sh.Link]
+			if !ok {
+				return ErrStrip
+			}
+			sh.Link = link
+		}
+		if sh.Info != 0 && (sh.Type == shtRel || sh.Type == shtRela || sh.Flags&shfInfoLink != 0) {
+			info, ok := index[sh.Info]
+			if !ok {
+				return ErrStrip
+			}
+			sh.Info = info
+		}
+		list = append(list, section{SectionHeader: sh})
+	}
+	list = append(list, extra...)
+
+	prefix := f.loadedSize()
+	for i, s := range list {
+		if s.isNull() || s.Flags&shfAlloc != 0 || s.isName() || s.Data != nil {
+			continue
+		}
+		if s.Offset < prefix && s.EndAt() > prefix && !s.isEmpty() {
+			return ErrStrip
+		}
+		if s.Offset >= prefix && !s.isEmpty() {
+			data, err := readSection(f.reader, s.SectionHeader)
+			if err != nil {
+				return err
+			}
+			list[i].Data = data
+		}
+	}
+	names := writeNames(list)
+
+	buf, err := readSection(f.reader, SectionHeader{Size: prefix})
+	if err != nil {
+		return err
+	}
+	out := bytes.NewBuffer(buf)
+	for i, s := range list {
+		if s.isName() {
+			list[i].Data = names
+			list[i].Size = uint64(len(names))
+			s = list[i]
+		}
+		if s.Data == nil {
+			if s.isEmpty() && s.Flags&shfAlloc == 0 {
+				list[i].Offset = uint64(out.Len())
+			}
+			continue
+		}
+		pad(out, s.AddrAlign)
+		list[i].Offset = uint64(out.Len())
+		out.Write(s.Data)
+	}
+	if f.Is32() {
+		pad(out, 4)
+	} else {
+		pad(out, 8)
+	}
+	var (
+		offset = out.Len()
+		order  = f.ByteOrder()
+		shstr  = slices.IndexFunc(list, func(s section) bool {
+			return s.isName()
+		})
+	)
+	if err := writeSectionHeaders(f, list, out); err != nil {
+		return err
+	}
+	body := out.Bytes()
+	if f.Is32() {
+		order.PutUint32(body[0x20:], uint32(offset))
+		order.PutUint16(body[0x30:], uint16(len(list)))
+		order.PutUint16(body[0x32:], uint16(shstr))
+	} else {
+		order.PutUint64(body[0x28:], uint64(offset))
+		order.PutUint16(body[0x3c:], uint16(len(list)))
+		order.PutUint16(body[0x3e:], uint16(shstr))
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+func (f *File) loadedSize() uint64 {
+	size := uint64(f.Size)
+	if end := f.ProgramAddr + uint64(f.PhSize)*uint64(f.PhCount); end > size {
+		size = end
+	}
+	for _, ph := range f.Programs {
+		if end := ph.Offset + ph.FileSize; end > size {
+			size = end
+		}
+	}
+	for _, sh := range f.Sections {
+		if sh.Flags&shfAlloc == 0 || sh.isEmpty() {
+			continue
+		}
+		if end := sh.EndAt(); end > size {
+			size = end
+		}
+	}
+	return size
+}
+
+func pad(buf *bytes.Buffer, align uint64) {
+	if align <= 1 {
+		return
+	}
+	if n := uint64(buf.Len()) % align; n != 0 {
+		buf.Write(make([]byte, align-n))
+	}
+}
+
+func writeNames(list []section) []byte {
+	var (
+		buf  []byte
+		seen = make(map[string]uint32)
+	)
+	buf = append(buf, 0)
+	seen[""] = 0
+	for i, s := range list {
+		ix, ok := seen[s.Label]
+		if !ok {
+			ix = uint32(len(buf))
+			seen[s.Label] = ix
+			buf = append(buf, s.Label...)
+			buf = append(buf, 0)
+		}
+		list[i].Name = ix
+	}
+	return buf
+}
+
+func writeSectionHeaders(f *File, list []section, w io.Writer) error {
+	var tmp bytes.Buffer
+	for _, sh := range list {
+		binary.Write(&tmp, f.ByteOrder(), sh.Name)
+		binary.Write(&tmp, f.ByteOrder(), sh.Type)
+		if f.Is32() {
+			binary.Write(&tmp, f.ByteOrder(), uint32(sh.Flags))
+			binary.Write(&tmp, f.ByteOrder(), uint32(sh.Addr))
+			binary.Write(&tmp, f.ByteOrder(), uint32(sh.Offset))
+			binary.Write(&tmp, f.ByteOrder(), uint32(sh.Size))
+			binary.Write(&tmp, f.ByteOrder(), sh.Link)
+			binary.Write(&tmp, f.ByteOrder(), sh.Info)
+			binary.Write(&tmp, f.ByteOrder(), uint32(sh.AddrAlign))
+			binary.Write(&tmp, f.ByteOrder(), uint32(sh.EntSize))
+		} else {
+			binary.Write(&tmp, f.ByteOrder(), sh.Flags)
+			binary.Write(&tmp, f.ByteOrder(), sh.Addr)
+			binary.Write(&tmp, f.ByteOrder(), sh.Offset)
+			binary.Write(&tmp, f.ByteOrder(), sh.Size)
+			binary.Write(&tmp, f.ByteOrder(), sh.Link)
+			binary.Write(&tmp, f.ByteOrder(), sh.Info)
+			binary.Write(&tmp, f.ByteOrder(), sh.AddrAlign)
+			binary.Write(&tmp, f.ByteOrder(), sh.EntSize)
+		}
+	}
+	_, err := io.Copy(w, &tmp)
+	return err
+}
+
+func (s SectionHeader) isName() bool {
+	return s.Label == ".shstrtab"
+}
+
+func (s SectionHeader) isNull() bool {
+	return s.Type == shtNull
+}
+
+func (s SectionHeader) isEmpty() bool {
+	return s.Type == shtNobits
+}
+
+func (s SectionHeader) canStrip() bool {
+	if strings.HasPrefix(s.Label, ".debug") || strings.HasPrefix(s.Label, ".zdebug") {
+		return true
+	}
+	return s.Label == ".comment" || s.Label == ".symtab" || s.Label == ".strtab"
+}
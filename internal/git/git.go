@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -149,14 +150,21 @@ func (c *Config) GetRemoteURL(name string) string {
 	return c.Sections[ix].Values["url"]
 }
 
-var gitConfig *Config
+var (
+	gitConfig *Config
+	gitOnce   sync.Once
+	gitErr    error
+)
 
 func Load() error {
-	cfg, err := readConfig()
-	if err == nil {
-		gitConfig = cfg
-	}
-	return err
+	gitOnce.Do(func() {
+		cfg, err := readConfig()
+		if err == nil {
+			gitConfig = cfg
+		}
+		gitErr = err
+	})
+	return gitErr
 }
 
 func readConfig() (*Config, error) {
@@ -6,9 +6,11 @@ import (
 )
 
 const (
-	Deb = "deb"
-	Rpm = "rpm"
-	Apk = "apk"
+	Deb    = "deb"
+	Rpm    = "rpm"
+	Apk    = "apk"
+	Ipk    = "ipk"
+	Pacman = "arch"
 )
 
 const (
@@ -37,8 +39,9 @@ const (
 const copyrightFile = "copyright"
 
 const (
-	EnvMaintainerName = "PACK_MAINTAINER_NAME"
-	EnvMaintainerMail = "PACK_MAINTAINER_MAIL"
+	EnvMaintainerName  = "PACK_MAINTAINER_NAME"
+	EnvMaintainerMail  = "PACK_MAINTAINER_MAIL"
+	EnvSourceDateEpoch = "SOURCE_DATE_EPOCH"
 )
 
 const (
@@ -36,6 +36,7 @@ const (
 	optFileTarget      = "target"
 	optFilePerm        = "perm"
 	optFileCompress    = "compress"
+	optFileStrip       = "strip"
 	optFileConfig      = "config"
 	optLicense         = "license"
 	optCopyright       = "copyright"
@@ -83,6 +84,9 @@ const (
 	optPostInst        = "post-install"
 	optPostRem         = "post-remove"
 	optCheckPkg        = "check-package"
+	optAutoDepends     = "auto-depends"
+	optShlibs          = "shlibs"
+	optStrip           = "strip"
 )
 
 var errSkip = errors.New("skip")
@@ -99,6 +103,7 @@ type DecoderConfig struct {
 	IgnoreFile string
 	Packfile   string
 	Type       string
+	Arch       string
 	Licenses   string
 
 	EnvFile string
@@ -120,6 +125,7 @@ func (d DecoderConfig) getMatcher() (glob.Matcher, error) {
 type Decoder struct {
 	context string
 	file    string
+	arch    string
 	nested  int
 	macros  *Environ
 	parent  *Decoder
@@ -132,7 +138,8 @@ type Decoder struct {
 	peek         Token
 	templateMode bool
 
-	licenses *template.Template
+	licenses  *template.Template
+	locations *Locations
 
 	env *Environ
 }
@@ -151,8 +158,9 @@ func NewDecoder(context string, config *DecoderConfig) (*Decoder, error) {
 		return nil, err
 	}
 
-	d := createDecoder(r, context, defaultEnv())
+	d := createDecoder(r, context, defaultEnv(config))
 	d.file = r.Name()
+	d.arch = config.Arch
 	d.ignore, err = config.getMatcher()
 	if err != nil {
 		return nil, err
@@ -180,6 +188,7 @@ func createDecoder(r io.Reader, context string, env *Environ) *Decoder {
 		scan:         Scan(r),
 		env:          Enclosed(env),
 		macros:       Empty(),
+		locations:    emptyLocations(),
 	}
 
 	return &d
@@ -194,6 +203,9 @@ func (d *Decoder) Decode() (*Package, error) {
 		License:  DefaultLicense,
 		Arch:     ArchNo,
 	}
+	if d.arch != "" {
+		pkg.Arch = d.arch
+	}
 	return &pkg, d.DecodeInto(&pkg)
 }
 
@@ -201,6 +213,17 @@ func (d *Decoder) DecodeInto(pkg *Package) error {
 	return d.decode(pkg)
 }
 
+func (d *Decoder) Locations() *Locations {
+	return d.locations
+}
+
+func (d *Decoder) Location() Location {
+	return Location{
+		File:     d.file,
+		Position: d.curr.Position,
+	}
+}
+
 func (d *Decoder) decode(pkg *Package) error {
 	for !d.done() {
 		d.skipComment()
@@ -244,7 +267,7 @@ func (d *Decoder) decodeLicenseFromObject(pkg *Package) error {
 				Target:  filepath.Join(DirDoc, pkg.Name, copyrightFile),
 				Perm:    PermFile,
 				Size:    int64(len(text)),
-				Lastmod: time.Now(),
+				Lastmod: BuildTime(),
 				Flags:   FileFlagDoc,
 			}
 			pkg.Files = append(pkg.Files, res)
@@ -287,7 +310,7 @@ func (d *Decoder) decodeLicenseFromTemplate(pkg *Package) error {
 		Author  string
 		Package string
 	}{
-		Date:    time.Now(),
+		Date:    BuildTime(),
 		Author:  pkg.Maintainer.Name,
 		Package: pkg.Name,
 	}
@@ -302,7 +325,7 @@ func (d *Decoder) decodeLicenseFromTemplate(pkg *Package) error {
 		Target:  filepath.Join(DirDoc, pkg.Name, copyrightFile),
 		Perm:    PermFile,
 		Size:    int64(str.Len()),
-		Lastmod: time.Now(),
+		Lastmod: BuildTime(),
 		Flags:   FileFlagDoc,
 	}
 	pkg.Files = append(pkg.Files, file)
@@ -543,6 +566,8 @@ func (d *Decoder) decodeFile(pkg *Package) error {
 			res.Perm, err = strconv.ParseInt(perm, 0, 64)
 		case optFileCompress:
 			res.Compress, err = d.decodeBool()
+		case optFileStrip:
+			res.Strip, err = d.decodeBool()
 		default:
 			err = fmt.Errorf("file: %s unsupported option", option)
 		}
@@ -562,6 +587,7 @@ func (d *Decoder) decodeFile(pkg *Package) error {
 		}
 		r.Perm = res.Perm
 		r.Compress = res.Compress
+		r.Strip = res.Strip
 		r.Flags = res.Flags
 
 		file := r.Local.(*os.File)
@@ -618,9 +644,21 @@ func (d *Decoder) decodeCompilerFromObject(pkg *Package) error {
 func (d *Decoder) decodeOption(pkg *Package) error {
 	var (
 		option = d.getCurrentLiteral()
+		where  = d.Location()
+		files  = len(pkg.Files)
+		deps   = len(pkg.Depends)
 		err    error
 	)
 	d.next()
+	defer func() {
+		d.locations.Options[option] = where
+		for range pkg.Files[files:] {
+			d.locations.Files = append(d.locations.Files, where)
+		}
+		for range pkg.Depends[deps:] {
+			d.locations.Depends = append(d.locations.Depends, where)
+		}
+	}()
 	switch option {
 	case optSetup:
 		pkg.Setup, err = d.decodeString()
@@ -672,6 +710,15 @@ func (d *Decoder) decodeOption(pkg *Package) error {
 		pkg.PreRem, err = d.decodeString()
 	case optPostRem:
 		pkg.PostRem, err = d.decodeString()
+	case optStrip:
+		pkg.Strip, err = d.decodeBool()
+	case optAutoDepends:
+		pkg.AutoDepends, err = d.decodeBool()
+	case optShlibs:
+		var file string
+		if file, err = d.decodeString(); err == nil {
+			pkg.Shlibs = append(pkg.Shlibs, filepath.Join(d.context, file))
+		}
 	case optCheckPkg:
 	default:
 		err = fmt.Errorf("package %s unsupported option", option)
@@ -683,7 +730,18 @@ func (d *Decoder) decodeBool() (bool, error) {
 	if !d.is(Boolean) {
 		return false, fmt.Errorf("value can not be used as a boolean")
 	}
-	ok, err := strconv.ParseBool(d.getCurrentLiteral())
+	var (
+		ok  bool
+		err error
+	)
+	switch lit := d.getCurrentLiteral(); lit {
+	case "on":
+		ok = true
+	case "off":
+		ok = false
+	default:
+		ok, err = strconv.ParseBool(lit)
+	}
 	if err != nil {
 		return false, err
 	}
@@ -975,6 +1033,7 @@ func (d *Decoder) executeInclude(pkg *Package) error {
 	}
 	sub.file = r.Name()
 	sub.licenses = d.licenses
+	sub.locations = d.locations
 	sub.next()
 	sub.next()
 	return sub.DecodeInto(pkg)
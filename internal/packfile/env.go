@@ -20,9 +20,16 @@ func Enclosed(parent *Environ) *Environ {
 	return &env
 }
 
-func defaultEnv() *Environ {
+func defaultEnv(config *DecoderConfig) *Environ {
 	env := Empty()
 
+	arch := config.Arch
+	if arch == "" {
+		arch = ArchNo
+	}
+	env.Define("kind", config.Type)
+	env.Define("arch", arch)
+
 	env.Define("arch64", Arch64)
 	env.Define("arch32", Arch32)
 	env.Define("noarch", ArchNo)
@@ -41,7 +48,7 @@ func defaultEnv() *Environ {
 
 func (e *Environ) Define(ident string, value any) error {
 	if e.readonly {
-		return fmt.Errorf("%s can not be modified as it is readonly")
+		return fmt.Errorf("%s can not be modified as it is readonly", ident)
 	}
 	_, ok := e.values[ident]
 	if ok {
@@ -0,0 +1,60 @@
+package packfile
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const licenseThreshold = 0.8
+
+var licenseActions = regexp.MustCompile(`{{.*?}}`)
+
+func GuessLicense(text string) (string, bool) {
+	files, err := fs.Glob(licenseFiles, "licenses/*.tpl")
+	if err != nil {
+		return "", false
+	}
+	var (
+		words = getLicenseWords(text)
+		best  string
+		score float64
+	)
+	for _, f := range files {
+		buf, err := fs.ReadFile(licenseFiles, f)
+		if err != nil {
+			continue
+		}
+		other := getLicenseWords(licenseActions.ReplaceAllString(string(buf), ""))
+		if s := compareLicenseWords(words, other); s > score {
+			best, score = strings.TrimSuffix(path.Base(f), ".tpl"), s
+		}
+	}
+	return best, score >= licenseThreshold
+}
+
+func compareLicenseWords(left, right map[string]struct{}) float64 {
+	if len(left) == 0 || len(right) == 0 {
+		return 0
+	}
+	var common int
+	for w := range left {
+		if _, ok := right[w]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(left)+len(right)-common)
+}
+
+func getLicenseWords(text string) map[string]struct{} {
+	words := make(map[string]struct{})
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range fields {
+		words[w] = struct{}{}
+	}
+	return words
+}
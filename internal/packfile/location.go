@@ -0,0 +1,29 @@
+package packfile
+
+import "fmt"
+
+type Location struct {
+	File string
+	Position
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+type Locations struct {
+	Options map[string]Location
+	Depends []Location
+	Files   []Location
+}
+
+func emptyLocations() *Locations {
+	return &Locations{
+		Options: make(map[string]Location),
+	}
+}
+
+func (l *Locations) Option(name string) (Location, bool) {
+	pos, ok := l.Options[name]
+	return pos, ok
+}
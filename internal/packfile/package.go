@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,12 +17,34 @@ type Maintainer struct {
 	Email string
 }
 
+func ParseMaintainer(str string) Maintainer {
+	var (
+		m               Maintainer
+		name, email, ok = strings.Cut(str, "<")
+	)
+	m.Name = strings.TrimSpace(name)
+	if ok {
+		email, _, _ = strings.Cut(email, ">")
+		m.Email = strings.TrimSpace(email)
+	}
+	return m
+}
+
+func (m Maintainer) String() string {
+	if m.Email == "" {
+		return m.Name
+	}
+	return fmt.Sprintf("%s <%s>", m.Name, m.Email)
+}
+
 type Dependency struct {
 	Package    string
 	Constraint string // gt, ge, lt, le,...
 	Version    string
 	Arch       string
-	Type       string // breaks, suggests, recommands,...
+	Type       string // breaks, suggests, recommends,...
+
+	Alternatives []Dependency
 }
 
 type Change struct {
@@ -33,8 +59,10 @@ type Resource struct {
 	Path     string
 	Local    io.ReadCloser
 	Target   string
+	Link     string
 	Perm     int64
 	Compress bool
+	Strip    bool
 
 	Flags int64
 
@@ -55,6 +83,23 @@ func (r Resource) IsDirectory() bool {
 	return r.Flags&FileFlagDir == FileFlagDir
 }
 
+func (r Resource) IsSymlink() bool {
+	return r.Link != ""
+}
+
+func PruneDirs(files []Resource) []Resource {
+	parents := make(map[string]struct{})
+	for _, r := range files {
+		for dir := path.Dir(r.Target); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			parents[dir] = struct{}{}
+		}
+	}
+	return slices.DeleteFunc(files, func(r Resource) bool {
+		_, ok := parents[strings.TrimSuffix(r.Target, "/")]
+		return ok && r.IsDirectory()
+	})
+}
+
 type Compiler struct {
 	Name    string
 	Version string
@@ -76,7 +121,10 @@ type Package struct {
 	BuildWith   Compiler
 	PackageType string
 
-	Essential bool
+	Essential   bool
+	AutoDepends bool
+	Strip       bool
+	Shlibs      []string
 
 	Arch     string
 	Os       string
@@ -100,6 +148,10 @@ type Package struct {
 }
 
 func Load(file, context string) (*Package, error) {
+	return LoadTarget(file, context, "", "")
+}
+
+func LoadTarget(file, context, kind, arch string) (*Package, error) {
 	r, err := os.Open(file)
 	if err != nil {
 		return nil, err
@@ -108,6 +160,8 @@ func Load(file, context string) (*Package, error) {
 
 	cfg := DecoderConfig{
 		Packfile: file,
+		Type:     kind,
+		Arch:     arch,
 	}
 
 	d, err := NewDecoder(context, &cfg)
@@ -129,6 +183,31 @@ func (p *Package) Split() []*Package {
 	return []*Package{&k, p.OnlyDocs()}
 }
 
+func (p *Package) Debug(suffix string, files []Resource) *Package {
+	k := *p
+	k.Name = fmt.Sprintf("%s%s", p.Name, suffix)
+	k.Summary = fmt.Sprintf("debug symbols for %s", p.Name)
+	k.Desc = fmt.Sprintf("This package contains the debugging symbols of %s.", p.Name)
+	k.Section = "debug"
+	k.Files = files
+	k.Changes = nil
+	k.AutoDepends = false
+	k.Strip = false
+	k.PreInst = ""
+	k.PostInst = ""
+	k.PreRem = ""
+	k.PostRem = ""
+	k.Depends = []Dependency{
+		{
+			Package:    p.Name,
+			Constraint: ConstraintEq,
+			Version:    p.Version,
+			Type:       "depends",
+		},
+	}
+	return &k
+}
+
 func (p *Package) OnlyDocs() *Package {
 	k := *p
 	k.Name = fmt.Sprintf("%s-doc", k.Name)
@@ -202,3 +281,49 @@ func (p *Package) TotalSize() int64 {
 func (p *Package) PackageName() string {
 	return fmt.Sprintf("%s-%s", p.Name, p.Version)
 }
+
+func SourceDateEpoch() (time.Time, bool) {
+	str := os.Getenv(EnvSourceDateEpoch)
+	if str == "" {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0).UTC(), true
+}
+
+func BuildTime() time.Time {
+	if when, ok := SourceDateEpoch(); ok {
+		return when
+	}
+	return time.Now()
+}
+
+func ClampTime(when, epoch time.Time) time.Time {
+	if when.IsZero() || when.After(epoch) {
+		return epoch
+	}
+	return when
+}
+
+func WriteFile(file string, body []byte) error {
+	w, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(w.Name())
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Chmod(PermFile); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return os.Rename(w.Name(), file)
+}
@@ -0,0 +1,200 @@
+package pacman
+
+import (
+	"archive/tar"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/tape"
+)
+
+func Content(file string) ([]*tape.Header, error) {
+	r, err := openFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var list []*tape.Header
+	for {
+		h, err := r.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if isMetaFile(h.Name) {
+			continue
+		}
+		hdr := tape.Header{
+			Filename: h.Name,
+			Size:     h.Size,
+			Mode:     h.Mode,
+			Uid:      int64(h.Uid),
+			Gid:      int64(h.Gid),
+			ModTime:  h.ModTime,
+		}
+		if h.Typeflag == tar.TypeDir {
+			hdr.Mode |= int64(os.ModeDir)
+		}
+		list = append(list, &hdr)
+	}
+	return list, nil
+}
+
+type PackageInfo struct {
+	packfile.Package
+
+	Size      int64
+	BuildTime time.Time
+	Base      string
+}
+
+func Info(file string) (*PackageInfo, error) {
+	r, err := openFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	for {
+		h, err := r.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if h.Name == pkgInfoFile {
+			return parseInfo(r)
+		}
+	}
+	return nil, fmt.Errorf("%s: file not found in package", pkgInfoFile)
+}
+
+func parseInfo(r io.Reader) (*PackageInfo, error) {
+	var (
+		scan = bufio.NewScanner(r)
+		pkg  PackageInfo
+	)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		field, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch field = strings.TrimSpace(field); field {
+		case "pkgname":
+			pkg.Name = value
+		case "pkgbase":
+			pkg.Base = value
+		case "pkgver":
+			pkg.Version = value
+			if ix := strings.LastIndex(value, "-"); ix > 0 {
+				pkg.Version = value[:ix]
+				pkg.Release = value[ix+1:]
+			}
+		case "pkgdesc":
+			pkg.Summary = value
+		case "url":
+			pkg.Home = value
+		case "builddate":
+			unix, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			pkg.BuildTime = time.Unix(unix, 0)
+		case "packager":
+			pkg.Maintainer = packfile.ParseMaintainer(value)
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			pkg.Size = size
+		case "arch":
+			pkg.Arch = value
+		case "license":
+			pkg.License = value
+		case "depend", "conflict", "provides", "replaces":
+			dep := parseDependency(value)
+			dep.Type = field
+			switch field {
+			case "depend":
+				dep.Type = "depends"
+			case "conflict":
+				dep.Type = "conflicts"
+			default:
+			}
+			pkg.Depends = append(pkg.Depends, dep)
+		case "optdepend":
+			name, _, _ := strings.Cut(value, ":")
+			dep := parseDependency(strings.TrimSpace(name))
+			dep.Type = "suggests"
+			pkg.Depends = append(pkg.Depends, dep)
+		default:
+		}
+	}
+	return &pkg, scan.Err()
+}
+
+func parseDependency(str string) packfile.Dependency {
+	var dep packfile.Dependency
+	ix := strings.IndexAny(str, "<>=")
+	if ix < 0 {
+		dep.Package = str
+		return dep
+	}
+	dep.Package = str[:ix]
+	op := str[ix:]
+	jx := strings.IndexFunc(op, func(r rune) bool {
+		return !strings.ContainsRune("<>=", r)
+	})
+	if jx < 0 {
+		return dep
+	}
+	dep.Version = op[jx:]
+	switch op[:jx] {
+	case "=":
+		dep.Constraint = packfile.ConstraintEq
+	case ">":
+		dep.Constraint = packfile.ConstraintGt
+	case ">=":
+		dep.Constraint = packfile.ConstraintGe
+	case "<":
+		dep.Constraint = packfile.ConstraintLt
+	case "<=":
+		dep.Constraint = packfile.ConstraintLe
+	default:
+	}
+	return dep
+}
+
+func Dependencies(file string) ([]packfile.Dependency, error) {
+	pkg, err := Info(file)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.Depends, nil
+}
+
+func isMetaFile(name string) bool {
+	switch name {
+	case pkgInfoFile, buildInfoFile, mtreeFile, installFile, ".CHANGELOG":
+		return true
+	default:
+		return false
+	}
+}
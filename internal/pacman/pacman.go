@@ -0,0 +1,478 @@
+package pacman
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/midbel/packit/internal/packfile"
+)
+
+const (
+	pkgInfoFile   = ".PKGINFO"
+	buildInfoFile = ".BUILDINFO"
+	mtreeFile     = ".MTREE"
+	installFile   = ".INSTALL"
+)
+
+const Extension = ".pkg.tar.zst"
+
+const reproducibleDir = "/build"
+
+//go:embed templates/pkginfo.tpl
+var pkginfoFile string
+
+//go:embed templates/buildinfo.tpl
+var buildinfoFile string
+
+type PacmanBuilder struct {
+	writer       io.Writer
+	buildTime    time.Time
+	reproducible bool
+}
+
+func Build(w io.Writer) (*PacmanBuilder, error) {
+	b := PacmanBuilder{
+		writer:    w,
+		buildTime: time.Now(),
+	}
+	return &b, nil
+}
+
+func (b *PacmanBuilder) SetSourceDateEpoch(when time.Time) {
+	b.buildTime = when
+	b.reproducible = true
+}
+
+func (b *PacmanBuilder) Build(p *packfile.Package) error {
+	if err := b.setup(p); err != nil {
+		return err
+	}
+	if err := b.build(p); err != nil {
+		return err
+	}
+	return b.teardown(p)
+}
+
+func (b *PacmanBuilder) setup(pkg *packfile.Package) error {
+	if pkg.Setup == "" {
+		return nil
+	}
+	scan := bufio.NewScanner(strings.NewReader(pkg.Setup))
+	for scan.Scan() {
+		cmd := exec.Command("sh", "-c", scan.Text())
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *PacmanBuilder) teardown(pkg *packfile.Package) error {
+	if pkg.Teardown == "" {
+		return nil
+	}
+	scan := bufio.NewScanner(strings.NewReader(pkg.Teardown))
+	for scan.Scan() {
+		cmd := exec.Command("sh", "-c", scan.Text())
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type entry struct {
+	*tar.Header
+	Body io.Reader
+	Sha  []byte
+	Md5  []byte
+}
+
+func (b *PacmanBuilder) build(p *packfile.Package) error {
+	files, err := collectFiles(p)
+	if err != nil {
+		return err
+	}
+	var meta []entry
+	for _, fn := range []func(*packfile.Package) (*entry, error){b.writeInfo, b.writeBuildInfo, b.writeInstall} {
+		e, err := fn(p)
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			meta = append(meta, *e)
+		}
+	}
+	mtree, err := b.writeMtree(append(slices.Clone(meta), files...))
+	if err != nil {
+		return err
+	}
+	meta = slices.Insert(meta, 2, *mtree)
+
+	z, err := zstd.NewWriter(b.writer)
+	if err != nil {
+		return err
+	}
+	w := tar.NewWriter(z)
+	for _, e := range append(meta, files...) {
+		if err := w.WriteHeader(e.Header); err != nil {
+			return err
+		}
+		if e.Body == nil {
+			continue
+		}
+		if _, err := io.Copy(w, e.Body); err != nil {
+			return err
+		}
+	}
+	for _, r := range p.Files {
+		if r.Local != nil {
+			r.Local.Close()
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+func (b *PacmanBuilder) writeInfo(p *packfile.Package) (*entry, error) {
+	ctx := struct {
+		*packfile.Package
+		BuildDate int64
+	}{
+		Package:   p,
+		BuildDate: b.buildTime.Unix(),
+	}
+	return b.executeTemplate(pkgInfoFile, pkginfoFile, ctx)
+}
+
+func (b *PacmanBuilder) writeBuildInfo(p *packfile.Package) (*entry, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if b.reproducible {
+		dir = reproducibleDir
+	}
+	ctx := struct {
+		*packfile.Package
+		BuildDate int64
+		BuildDir  string
+	}{
+		Package:   p,
+		BuildDate: b.buildTime.Unix(),
+		BuildDir:  dir,
+	}
+	return b.executeTemplate(buildInfoFile, buildinfoFile, ctx)
+}
+
+func (b *PacmanBuilder) executeTemplate(file, text string, ctx any) (*entry, error) {
+	fn := template.FuncMap{
+		"pkgver":     getPackageVersion,
+		"arch":       getPackageArch,
+		"dependency": formatDependency,
+		"backup":     getBackupFiles,
+	}
+	t, err := template.New(file).Funcs(fn).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, ctx); err != nil {
+		return nil, err
+	}
+	return makeEntry(file, buf.Bytes(), packfile.PermFile, b.buildTime), nil
+}
+
+func (b *PacmanBuilder) writeInstall(pkg *packfile.Package) (*entry, error) {
+	list := []struct {
+		Script string
+		Func   string
+	}{
+		{
+			Script: pkg.PreInst,
+			Func:   "pre_install",
+		},
+		{
+			Script: pkg.PostInst,
+			Func:   "post_install",
+		},
+		{
+			Script: pkg.PreRem,
+			Func:   "pre_remove",
+		},
+		{
+			Script: pkg.PostRem,
+			Func:   "post_remove",
+		},
+	}
+	var buf bytes.Buffer
+	for _, i := range list {
+		if i.Script == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s() {", i.Func)
+		fmt.Fprintln(&buf)
+		fmt.Fprintln(&buf, strings.TrimSpace(i.Script))
+		fmt.Fprintln(&buf, "}")
+		fmt.Fprintln(&buf)
+	}
+	if buf.Len() == 0 {
+		return nil, nil
+	}
+	return makeEntry(installFile, buf.Bytes(), packfile.PermFile, b.buildTime), nil
+}
+
+func (b *PacmanBuilder) writeMtree(list []entry) (*entry, error) {
+	var buf bytes.Buffer
+	z, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+
+	fmt.Fprintln(z, "#mtree")
+	fmt.Fprintln(z, "/set type=file uid=0 gid=0 mode=644")
+	for _, e := range list {
+		fmt.Fprintf(z, "./%s time=%d.0", escapeName(e.Name), e.ModTime.Unix())
+		if e.Mode != packfile.PermFile || e.Typeflag == tar.TypeDir {
+			fmt.Fprintf(z, " mode=%o", e.Mode)
+		}
+		switch e.Typeflag {
+		case tar.TypeDir:
+			fmt.Fprint(z, " type=dir")
+		case tar.TypeSymlink:
+			fmt.Fprintf(z, " type=link link=%s", escapeName(e.Linkname))
+		default:
+			fmt.Fprintf(z, " size=%d md5digest=%x sha256digest=%x", e.Size, e.Md5, e.Sha)
+		}
+		fmt.Fprintln(z)
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	e := makeEntry(mtreeFile, buf.Bytes(), packfile.PermFile, b.buildTime)
+	return e, nil
+}
+
+func collectFiles(pkg *packfile.Package) ([]entry, error) {
+	slices.SortFunc(pkg.Files, func(a, b packfile.Resource) int {
+		return strings.Compare(a.Target, b.Target)
+	})
+	var (
+		list []entry
+		seen = make(map[string]struct{})
+	)
+	for _, r := range pkg.Files {
+		if r.Target == "" {
+			continue
+		}
+		dir := filepath.Dir(r.Target)
+		if _, ok := seen[dir]; len(dir) > 0 && !ok {
+			paths := strings.Split(dir, string(filepath.Separator))
+			for i := range paths {
+				if paths[i] == "" || paths[i] == "." {
+					continue
+				}
+				target := strings.Join(paths[:i+1], "/")
+				if _, ok := seen[target]; ok {
+					continue
+				}
+				seen[target] = struct{}{}
+				h := makeTarHeader(target, 0, packfile.PermDir, r.Lastmod)
+				h.Typeflag = tar.TypeDir
+				list = append(list, entry{Header: h})
+			}
+		}
+		if r.IsDirectory() {
+			if _, ok := seen[r.Target]; ok {
+				continue
+			}
+			seen[r.Target] = struct{}{}
+			perm := r.Perm
+			if perm == 0 {
+				perm = packfile.PermDir
+			}
+			h := makeTarHeader(r.Target, 0, int(perm), r.Lastmod)
+			h.Typeflag = tar.TypeDir
+			list = append(list, entry{Header: h})
+			continue
+		}
+		if r.IsSymlink() {
+			h := makeTarHeader(r.Target, 0, 0o777, r.Lastmod)
+			h.Typeflag = tar.TypeSymlink
+			h.Linkname = r.Link
+			list = append(list, entry{Header: h})
+			continue
+		}
+		rs, err := readResource(r)
+		if err != nil {
+			return nil, err
+		}
+		perm := r.Perm
+		if perm == 0 {
+			perm = packfile.GetPermissionFromPath(r.Target)
+		}
+		rs.Header = makeTarHeader(r.Target, int(r.Size), int(perm), r.Lastmod)
+		list = append(list, *rs)
+	}
+	return list, nil
+}
+
+func readResource(r packfile.Resource) (*entry, error) {
+	var (
+		sha = sha256.New()
+		md  = md5.New()
+		e   entry
+	)
+	if s, ok := r.Local.(io.ReadSeeker); ok {
+		if _, err := io.Copy(io.MultiWriter(sha, md), s); err != nil {
+			return nil, err
+		}
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		e.Body = s
+	} else {
+		var buf bytes.Buffer
+		if _, err := io.Copy(io.MultiWriter(&buf, sha, md), r.Local); err != nil {
+			return nil, err
+		}
+		e.Body = &buf
+	}
+	e.Sha = sha.Sum(nil)
+	e.Md5 = md.Sum(nil)
+	return &e, nil
+}
+
+func makeEntry(file string, body []byte, perm int, when time.Time) *entry {
+	var (
+		sha = sha256.Sum256(body)
+		md  = md5.Sum(body)
+	)
+	e := entry{
+		Header: makeTarHeader(file, len(body), perm, when),
+		Body:   bytes.NewReader(body),
+		Sha:    sha[:],
+		Md5:    md[:],
+	}
+	return &e
+}
+
+func makeTarHeader(file string, size, perm int, when time.Time) *tar.Header {
+	if when.IsZero() {
+		when = time.Now()
+	}
+	h := tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     file,
+		Size:     int64(size),
+		Uid:      0,
+		Gid:      0,
+		Uname:    packfile.DefaultUser,
+		Gname:    packfile.DefaultGroup,
+		ModTime:  when.Truncate(time.Second),
+		Mode:     int64(perm),
+	}
+	return &h
+}
+
+func escapeName(name string) string {
+	var str strings.Builder
+	for _, c := range []byte(name) {
+		if c <= ' ' || c >= 0x7f || c == '\\' || c == '#' || c == '=' {
+			fmt.Fprintf(&str, "\\%03o", c)
+			continue
+		}
+		str.WriteByte(c)
+	}
+	return str.String()
+}
+
+func getPackageVersion(pkg *packfile.Package) string {
+	var (
+		version = pkg.Version
+		release = pkg.Release
+		epoch   string
+	)
+	if e, rest, ok := strings.Cut(version, ":"); ok {
+		if _, err := strconv.Atoi(e); err == nil {
+			epoch, version = e, rest
+		}
+	}
+	if ix := strings.LastIndex(version, "-"); ix > 0 && release == "" {
+		version, release = version[:ix], version[ix+1:]
+	}
+	if release == "" {
+		release = "1"
+	}
+	if epoch != "" {
+		return fmt.Sprintf("%s:%s-%s", epoch, version, release)
+	}
+	return fmt.Sprintf("%s-%s", version, release)
+}
+
+func getBackupFiles(pkg *packfile.Package) []string {
+	var list []string
+	for _, r := range pkg.Files {
+		if r.IsConfig() {
+			list = append(list, strings.TrimPrefix(r.Target, "/"))
+		}
+	}
+	return list
+}
+
+func getPackageArch(arch string) string {
+	switch arch {
+	case packfile.Arch64:
+		return "x86_64"
+	case packfile.Arch32:
+		return "i686"
+	case "arm64":
+		return "aarch64"
+	case "armhf":
+		return "armv7h"
+	case packfile.ArchAll, packfile.ArchNo, "":
+		return "any"
+	default:
+		return arch
+	}
+}
+
+func formatDependency(dp packfile.Dependency) string {
+	if dp.Version == "" {
+		return dp.Package
+	}
+	return dp.Package + formatDependencyConstraint(dp.Constraint) + dp.Version
+}
+
+func formatDependencyConstraint(op string) string {
+	switch op {
+	case packfile.ConstraintEq:
+		op = "="
+	case packfile.ConstraintGt:
+		op = ">"
+	case packfile.ConstraintGe, "":
+		op = ">="
+	case packfile.ConstraintLt:
+		op = "<"
+	case packfile.ConstraintLe:
+		op = "<="
+	default:
+		op = "="
+	}
+	return op
+}
@@ -0,0 +1,126 @@
+package pacman
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type reader struct {
+	*tar.Reader
+
+	file *os.File
+	z    *zstd.Decoder
+}
+
+func openFile(file string) (*reader, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	z, err := zstd.NewReader(r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	rs := reader{
+		Reader: tar.NewReader(z),
+		file:   r,
+		z:      z,
+	}
+	return &rs, nil
+}
+
+func (r *reader) Close() error {
+	r.z.Close()
+	return r.file.Close()
+}
+
+type mtreeEntry struct {
+	Name   string
+	Type   string
+	Mode   int64
+	Size   int64
+	Sha256 string
+	Link   string
+}
+
+func readMtree(r io.Reader) (map[string]mtreeEntry, error) {
+	z, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+
+	var (
+		scan  = bufio.NewScanner(z)
+		list  = make(map[string]mtreeEntry)
+		attrs = make(map[string]string)
+	)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "/set":
+			for _, f := range fields[1:] {
+				k, v, _ := strings.Cut(f, "=")
+				attrs[k] = v
+			}
+			continue
+		case "/unset":
+			for _, f := range fields[1:] {
+				delete(attrs, f)
+			}
+			continue
+		default:
+		}
+		values := make(map[string]string)
+		for k, v := range attrs {
+			values[k] = v
+		}
+		for _, f := range fields[1:] {
+			k, v, _ := strings.Cut(f, "=")
+			values[k] = v
+		}
+		e := mtreeEntry{
+			Name:   strings.TrimPrefix(unescapeName(fields[0]), "./"),
+			Type:   values["type"],
+			Sha256: values["sha256digest"],
+			Link:   unescapeName(values["link"]),
+		}
+		if e.Mode, err = strconv.ParseInt(values["mode"], 8, 64); err != nil {
+			return nil, err
+		}
+		if str, ok := values["size"]; ok {
+			if e.Size, err = strconv.ParseInt(str, 10, 64); err != nil {
+				return nil, err
+			}
+		}
+		list[e.Name] = e
+	}
+	return list, scan.Err()
+}
+
+func unescapeName(name string) string {
+	var str strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+4 <= len(name) {
+			if c, err := strconv.ParseUint(name[i+1:i+4], 8, 8); err == nil {
+				str.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		str.WriteByte(name[i])
+	}
+	return str.String()
+}
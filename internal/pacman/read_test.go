@@ -0,0 +1,77 @@
+package pacman
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+)
+
+func TestReadMtree(t *testing.T) {
+	tests := []struct {
+		input string
+		want  map[string]mtreeEntry
+	}{
+		{
+			input: "#mtree\n/set type=file uid=0 gid=0 mode=644\n./.PKGINFO time=1.0 size=10 sha256digest=aa\n",
+			want: map[string]mtreeEntry{
+				".PKGINFO": {Name: ".PKGINFO", Type: "file", Mode: 0o644, Size: 10, Sha256: "aa"},
+			},
+		},
+		{
+			input: "#mtree\n/set type=file uid=0 gid=0 mode=644\n./usr time=1.0 mode=755 type=dir\n./usr/bin/foo time=1.0 mode=755 size=3 md5digest=bb sha256digest=cc\n./usr/bin/bar time=1.0 mode=777 type=link link=foo\n",
+			want: map[string]mtreeEntry{
+				"usr":         {Name: "usr", Type: "dir", Mode: 0o755},
+				"usr/bin/foo": {Name: "usr/bin/foo", Type: "file", Mode: 0o755, Size: 3, Sha256: "cc"},
+				"usr/bin/bar": {Name: "usr/bin/bar", Type: "link", Mode: 0o777, Link: "foo"},
+			},
+		},
+		{
+			input: "#mtree\n/set type=file mode=644\n./a\\040b time=1.0 size=1 sha256digest=dd\n/unset type\n/set mode=600\n./c type=link link=a\\040b\n",
+			want: map[string]mtreeEntry{
+				"a b": {Name: "a b", Type: "file", Mode: 0o644, Size: 1, Sha256: "dd"},
+				"c":   {Name: "c", Type: "link", Mode: 0o600, Link: "a b"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := readMtree(compress(t, tt.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: want %+v, got %+v", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestReadMtreeInvalid(t *testing.T) {
+	tests := []string{
+		"#mtree\n./foo type=file mode=abc\n",
+		"#mtree\n./foo type=file mode=644 size=abc\n",
+	}
+	for _, input := range tests {
+		if _, err := readMtree(compress(t, input)); err == nil {
+			t.Errorf("%q: expected error", input)
+		}
+	}
+	if _, err := readMtree(bytes.NewReader([]byte("#mtree\n"))); err == nil {
+		t.Errorf("uncompressed mtree: expected error")
+	}
+}
+
+func compress(t *testing.T, str string) *bytes.Buffer {
+	t.Helper()
+	var (
+		buf bytes.Buffer
+		z   = gzip.NewWriter(&buf)
+	)
+	if _, err := z.Write([]byte(str)); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
@@ -0,0 +1,11 @@
+format = 2
+pkgname = {{.Name}}
+pkgbase = {{.Name}}
+pkgver = {{pkgver .Package}}
+pkgarch = {{arch .Arch}}
+{{with .Maintainer.Name}}packager = {{$.Maintainer}}
+{{end -}}
+builddate = {{.BuildDate}}
+builddir = {{.BuildDir}}
+startdir = {{.BuildDir}}
+buildtool = packit
@@ -0,0 +1,31 @@
+# Generated by packit
+pkgname = {{.Name}}
+pkgbase = {{.Name}}
+xdata = pkgtype=pkg
+pkgver = {{pkgver .Package}}
+pkgdesc = {{.Summary}}
+{{with .Home}}url = {{.}}
+{{end -}}
+builddate = {{.BuildDate}}
+{{with .Maintainer.Name}}packager = {{$.Maintainer}}
+{{end -}}
+size = {{.TotalSize}}
+arch = {{arch .Arch}}
+{{with .License}}license = {{.}}
+{{end -}}
+{{range .Replaces}}replaces = {{.Package}}
+{{end -}}
+{{range .Conflicts}}conflict = {{dependency .}}
+{{end -}}
+{{range .Breaks}}conflict = {{dependency .}}
+{{end -}}
+{{range .Provides}}provides = {{dependency .}}
+{{end -}}
+{{range .Requires}}depend = {{dependency .}}
+{{end -}}
+{{range .Recommends}}optdepend = {{.Package}}
+{{end -}}
+{{range .Suggests}}optdepend = {{.Package}}
+{{end -}}
+{{range backup .Package}}backup = {{.}}
+{{end -}}
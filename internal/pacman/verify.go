@@ -0,0 +1,113 @@
+package pacman
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+func Check(file string) error {
+	r, err := openFile(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var (
+		mtree map[string]mtreeEntry
+		found = make(map[string]mtreeEntry)
+	)
+	for {
+		h, err := r.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		if h.Name == mtreeFile {
+			var buf bytes.Buffer
+			if _, err := io.Copy(&buf, r); err != nil {
+				return err
+			}
+			if mtree, err = readMtree(&buf); err != nil {
+				return err
+			}
+			continue
+		}
+		e := mtreeEntry{
+			Name: h.Name,
+			Mode: h.Mode & 0o7777,
+			Size: h.Size,
+			Type: "file",
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			e.Type = "dir"
+			e.Size = 0
+		case tar.TypeSymlink:
+			e.Type = "link"
+			e.Link = h.Linkname
+			e.Size = 0
+		case tar.TypeReg:
+			sum := sha256.New()
+			if _, err := io.Copy(sum, r); err != nil {
+				return err
+			}
+			e.Sha256 = hex.EncodeToString(sum.Sum(nil))
+		default:
+			continue
+		}
+		found[e.Name] = e
+	}
+	if mtree == nil {
+		return fmt.Errorf("%s: file not found in package", mtreeFile)
+	}
+	return checkFiles(mtree, found)
+}
+
+func checkFiles(mtree, found map[string]mtreeEntry) error {
+	var names []string
+	for name := range mtree {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		want := mtree[name]
+		got, ok := found[name]
+		if !ok {
+			return fmt.Errorf("%s: file missing from package", name)
+		}
+		delete(found, name)
+		if want.Type != got.Type {
+			return fmt.Errorf("%s: type mismatched (%s != %s)", name, want.Type, got.Type)
+		}
+		if want.Mode != got.Mode {
+			return fmt.Errorf("%s: mode mismatched (%o != %o)", name, want.Mode, got.Mode)
+		}
+		if want.Type == "dir" {
+			continue
+		}
+		if want.Type == "link" {
+			if want.Link != got.Link {
+				return fmt.Errorf("%s: link mismatched (%s != %s)", name, want.Link, got.Link)
+			}
+			continue
+		}
+		if want.Size != got.Size {
+			return fmt.Errorf("%s: size mismatched (%d != %d)", name, want.Size, got.Size)
+		}
+		if want.Sha256 != got.Sha256 {
+			return fmt.Errorf("%s: checksum mismatched (%s)", name, want.Sha256)
+		}
+	}
+	for name := range found {
+		return fmt.Errorf("%s: file not found in %s", name, mtreeFile)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package pgp
+
+import (
+	"bytes"
+	"crypto"
+	"encoding"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const EnvPassphrase = "PACKIT_SIGN_PASSPHRASE"
+
+type Key struct {
+	entity *openpgp.Entity
+}
+
+func ReadPrivateKey(file string) (*Key, error) {
+	list, err := readKeyring(file)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range list {
+		if e.PrivateKey == nil {
+			continue
+		}
+		if e.PrivateKey.Encrypted {
+			pass := os.Getenv(EnvPassphrase)
+			if pass == "" {
+				return nil, fmt.Errorf("%s: private key is encrypted (set %s)", file, EnvPassphrase)
+			}
+			if err := e.DecryptPrivateKeys([]byte(pass)); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+		return &Key{entity: e}, nil
+	}
+	return nil, fmt.Errorf("%s: no private key found", file)
+}
+
+func (k *Key) IsRSA() bool {
+	sk, ok := k.entity.SigningKey(getConfig().Now())
+	if !ok {
+		return false
+	}
+	switch sk.PublicKey.PubKeyAlgo {
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSASignOnly:
+		return true
+	default:
+		return false
+	}
+}
+
+func (k *Key) Sign(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := openpgp.DetachSign(&buf, k.entity, r, getConfig()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (k *Key) ArmoredSign(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&buf, k.entity, r, getConfig()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (k *Key) Clearsign(text []byte) ([]byte, error) {
+	cfg := getConfig()
+	sk, ok := k.entity.SigningKey(cfg.Now())
+	if !ok {
+		return nil, fmt.Errorf("no signing key found")
+	}
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, sk.PrivateKey, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(text); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (k *Key) Identity() string {
+	if id := k.entity.PrimaryIdentity(); id != nil {
+		return id.Name
+	}
+	return ""
+}
+
+type Keyring struct {
+	list openpgp.EntityList
+}
+
+func ReadKeyring(file string) (*Keyring, error) {
+	list, err := readKeyring(file)
+	if err != nil {
+		return nil, err
+	}
+	return &Keyring{list: list}, nil
+}
+
+func (k *Keyring) VerifyClearsign(msg []byte) ([]byte, error) {
+	b, _ := clearsign.Decode(msg)
+	if b == nil {
+		return nil, fmt.Errorf("signature: no signed message found")
+	}
+	if _, err := b.VerifySignature(k.list, nil); err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+	return b.Plaintext, nil
+}
+
+type Verifier struct {
+	sig  *packet.Signature
+	hash hash.Hash
+}
+
+func NewVerifier(sig []byte) (*Verifier, error) {
+	var r io.Reader = bytes.NewReader(sig)
+	if bytes.HasPrefix(sig, []byte("-----BEGIN")) {
+		b, err := armor.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		r = b.Body
+	}
+	p, err := packet.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := p.(*packet.Signature)
+	if !ok {
+		return nil, fmt.Errorf("signature packet expected")
+	}
+	h, err := s.PrepareVerify()
+	if err != nil {
+		return nil, err
+	}
+	v := Verifier{
+		sig:  s,
+		hash: h,
+	}
+	return &v, nil
+}
+
+func (v *Verifier) Write(b []byte) (int, error) {
+	return v.hash.Write(b)
+}
+
+func (v *Verifier) Verify(keyring *Keyring) error {
+	issuer := "unknown"
+	if v.sig.IssuerKeyId != nil {
+		issuer = fmt.Sprintf("%016X", *v.sig.IssuerKeyId)
+	}
+	keys := v.candidates(keyring)
+	if len(keys) == 0 {
+		return fmt.Errorf("signature: no public key found for key %s", issuer)
+	}
+	m, ok := v.hash.(encoding.BinaryMarshaler)
+	if !ok {
+		return fmt.Errorf("signature: hash state can not be saved")
+	}
+	state, err := m.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		h := v.sig.Hash.New()
+		if u, ok := h.(encoding.BinaryUnmarshaler); !ok || u.UnmarshalBinary(state) != nil {
+			return fmt.Errorf("signature: hash state can not be restored")
+		}
+		if err := k.VerifySignature(h, v.sig); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature: invalid signature (%s)", issuer)
+}
+
+func (v *Verifier) candidates(keyring *Keyring) []*packet.PublicKey {
+	var list []*packet.PublicKey
+	if v.sig.IssuerKeyId != nil {
+		for _, k := range keyring.list.KeysById(*v.sig.IssuerKeyId) {
+			list = append(list, k.PublicKey)
+		}
+		return list
+	}
+	for _, e := range keyring.list {
+		list = append(list, e.PrimaryKey)
+		for _, s := range e.Subkeys {
+			list = append(list, s.PublicKey)
+		}
+	}
+	return list
+}
+
+func readKeyring(file string) (openpgp.EntityList, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	const marker = "-----BEGIN"
+	if !bytes.Contains(buf, []byte(marker)) {
+		return openpgp.ReadKeyRing(bytes.NewReader(buf))
+	}
+	var list openpgp.EntityList
+	for len(buf) > 0 {
+		block := buf
+		if ix := bytes.Index(buf[1:], []byte(marker)); ix >= 0 {
+			block, buf = buf[:ix+1], buf[ix+1:]
+		} else {
+			buf = nil
+		}
+		es, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(block))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, es...)
+	}
+	return list, nil
+}
+
+func getConfig() *packet.Config {
+	return &packet.Config{
+		DefaultHash: crypto.SHA256,
+	}
+}
@@ -0,0 +1,122 @@
+package rpm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func DumpHeader(file string, w io.Writer) error {
+	r, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := readLead(r); err != nil {
+		return err
+	}
+	for _, section := range []string{"signature", "header"} {
+		var (
+			index bytes.Buffer
+			store bytes.Buffer
+		)
+		if err := readHeader(r, &index, &store, section == "signature"); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "[%s]", section)
+		fmt.Fprintln(w)
+		if err := dumpEntries(w, &index, bytes.NewReader(store.Bytes()), index.Len()/rpmEntryLen); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func dumpEntries(w io.Writer, index io.Reader, store io.ReadSeeker, total int) error {
+	for i := 0; i < total; i++ {
+		var (
+			tag    int32
+			kind   int32
+			offset int32
+			count  int32
+		)
+		binary.Read(index, binary.BigEndian, &tag)
+		binary.Read(index, binary.BigEndian, &kind)
+		binary.Read(index, binary.BigEndian, &offset)
+		binary.Read(index, binary.BigEndian, &count)
+
+		if _, err := store.Seek(int64(offset), io.SeekStart); err != nil {
+			return err
+		}
+		var values []string
+		switch kind {
+		case fieldChar, fieldInt8, fieldInt16, fieldInt32, fieldInt64:
+			typ := kind
+			if typ == fieldChar {
+				typ = fieldInt8
+			}
+			list, err := readIntArray(store, typ, count)
+			if err != nil {
+				return err
+			}
+			for _, v := range list {
+				values = append(values, strconv.FormatInt(v, 10))
+			}
+		case fieldString, fieldStrArray, fieldI18NString:
+			if kind == fieldString {
+				count = 1
+			}
+			list, err := readStringArray(store, count)
+			if err != nil {
+				return err
+			}
+			for _, v := range list {
+				values = append(values, strconv.Quote(v))
+			}
+		case fieldBinary:
+			buf := make([]byte, count)
+			if _, err := io.ReadFull(store, buf); err != nil {
+				return err
+			}
+			values = append(values, hex.EncodeToString(buf))
+		default:
+		}
+		fmt.Fprintf(w, "%d %s %d: %s", tag, getFieldName(kind), count, strings.Join(values, ", "))
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func getFieldName(kind int32) string {
+	switch kind {
+	case fieldNull:
+		return "NULL"
+	case fieldChar:
+		return "CHAR"
+	case fieldInt8:
+		return "INT8"
+	case fieldInt16:
+		return "INT16"
+	case fieldInt32:
+		return "INT32"
+	case fieldInt64:
+		return "INT64"
+	case fieldString:
+		return "STRING"
+	case fieldBinary:
+		return "BIN"
+	case fieldStrArray:
+		return "STRING_ARRAY"
+	case fieldI18NString:
+		return "I18NSTRING"
+	default:
+		return strconv.Itoa(int(kind))
+	}
+}
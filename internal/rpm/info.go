@@ -6,8 +6,11 @@ import (
 	"compress/gzip"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/midbel/packit/internal/packfile"
@@ -58,9 +61,14 @@ func Content(file string) ([]*tape.Header, error) {
 type PackageInfo struct {
 	packfile.Package
 
-	Size      int64
-	BuildTime time.Time
-	BuildHost string
+	Size        int64
+	ArchiveSize int64
+	Epoch       int64
+	SourceRpm   string
+	BuildTime   time.Time
+	BuildHost   string
+	Compressor  string
+	Dirs        []string
 }
 
 func Info(file string) (*PackageInfo, error) {
@@ -73,7 +81,11 @@ func Info(file string) (*PackageInfo, error) {
 	if err := readLead(r); err != nil {
 		return nil, err
 	}
-	if err := readHeader(r, io.Discard, io.Discard, true); err != nil {
+	var (
+		sigIndex bytes.Buffer
+		sigStore bytes.Buffer
+	)
+	if err := readHeader(r, &sigIndex, &sigStore, true); err != nil {
 		return nil, err
 	}
 	var (
@@ -83,15 +95,220 @@ func Info(file string) (*PackageInfo, error) {
 	if err := readHeader(r, &index, &store, false); err != nil {
 		return nil, err
 	}
-	return readPackage(&index, bytes.NewReader(store.Bytes()), index.Len()/16)
+	pkg, err := readPackage(&index, bytes.NewReader(store.Bytes()), index.Len()/16)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.ArchiveSize == 0 {
+		pkg.ArchiveSize, err = readPayloadSize(&sigIndex, sigStore.Bytes())
+	}
+	return pkg, err
+}
+
+func readPayloadSize(index io.Reader, store []byte) (int64, error) {
+	for {
+		var (
+			tag    int32
+			kind   int32
+			offset int32
+			count  int32
+		)
+		for _, v := range []*int32{&tag, &kind, &offset, &count} {
+			if err := binary.Read(index, binary.BigEndian, v); err != nil {
+				if errors.Is(err, io.EOF) {
+					return 0, nil
+				}
+				return 0, err
+			}
+		}
+		if tag != rpmSigPayload {
+			continue
+		}
+		if offset < 0 || int(offset) >= len(store) {
+			return 0, fmt.Errorf("signature: invalid offset for payload size (%d)", offset)
+		}
+		return readInt(bytes.NewReader(store[offset:]))
+	}
+}
+
+func Dependencies(file string) ([]packfile.Dependency, error) {
+	pkg, err := Info(file)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.Depends, nil
+}
+
+type rpmFiles struct {
+	sizes   []int64
+	modes   []int64
+	times   []int64
+	flags   []int64
+	indexes []int64
+	digests []string
+	bases   []string
+	dirs    []string
+}
+
+func (f rpmFiles) Resources() []packfile.Resource {
+	var list []packfile.Resource
+	for i := range f.bases {
+		if i >= len(f.indexes) || i >= len(f.modes) {
+			break
+		}
+		if f.modes[i]&rpmFileTypeMask == rpmFileTypeDir {
+			continue
+		}
+		ix := int(f.indexes[i])
+		if ix < 0 || ix >= len(f.dirs) {
+			continue
+		}
+		res := packfile.Resource{
+			Target: strings.TrimPrefix(path.Join(f.dirs[ix], f.bases[i]), "/"),
+			Perm:   f.modes[i] & 0o7777,
+		}
+		res.Path = res.Target
+		if i < len(f.sizes) {
+			res.Size = f.sizes[i]
+		}
+		if i < len(f.times) {
+			res.Lastmod = time.Unix(f.times[i], 0)
+		}
+		if i < len(f.flags) {
+			res.Flags = f.flags[i] &^ packfile.FileFlagDir
+		}
+		if i < len(f.digests) {
+			res.Hash = f.digests[i]
+		}
+		list = append(list, res)
+	}
+	return list
+}
+
+func (f rpmFiles) Directories() []string {
+	var list []string
+	for i := range f.bases {
+		if i >= len(f.indexes) || i >= len(f.modes) {
+			break
+		}
+		if f.modes[i]&rpmFileTypeMask != rpmFileTypeDir {
+			continue
+		}
+		ix := int(f.indexes[i])
+		if ix < 0 || ix >= len(f.dirs) {
+			continue
+		}
+		list = append(list, strings.TrimPrefix(path.Join(f.dirs[ix], f.bases[i]), "/"))
+	}
+	return list
+}
+
+type rpmChanges struct {
+	times []int64
+	names []string
+	texts []string
+}
+
+func (c rpmChanges) Changes() []packfile.Change {
+	var list []packfile.Change
+	for i := range c.names {
+		var (
+			chg      packfile.Change
+			who, ver = splitChangeName(c.names[i])
+		)
+		chg.Maintainer = packfile.ParseMaintainer(who)
+		chg.Version = ver
+		if i < len(c.times) {
+			chg.When = time.Unix(c.times[i], 0).UTC()
+		}
+		if i < len(c.texts) {
+			for _, line := range strings.Split(c.texts[i], "\n") {
+				line = strings.TrimSpace(line)
+				if str, ok := strings.CutPrefix(line, "- "); ok {
+					chg.Changes = append(chg.Changes, str)
+				} else if line != "" && chg.Summary == "" {
+					chg.Summary = line
+				}
+			}
+		}
+		list = append(list, chg)
+	}
+	return list
+}
+
+func splitChangeName(str string) (string, string) {
+	ix := strings.LastIndex(str, " - ")
+	if ix < 0 {
+		return str, ""
+	}
+	return strings.TrimSpace(str[:ix]), strings.TrimSpace(str[ix+3:])
+}
+
+var rpmDependencies = []struct {
+	Type    string
+	Name    int32
+	Version int32
+	Flags   int32
+}{
+	{Type: "depends", Name: rpmTagRequireName, Version: rpmTagRequireVersion, Flags: rpmTagRequireFlags},
+	{Type: "provides", Name: rpmTagProvideName, Version: rpmTagProvideVersion, Flags: rpmTagProvideFlags},
+	{Type: "conflicts", Name: rpmTagConflictName, Version: rpmTagConflictVersion, Flags: rpmTagConflictFlags},
+	{Type: "recommends", Name: rpmTagRecommendName, Version: rpmTagRecommendVersion, Flags: rpmTagRecommendFlags},
+	{Type: "suggests", Name: rpmTagSuggestName, Version: rpmTagSuggestVersion, Flags: rpmTagSuggestFlags},
+	{Type: "enhances", Name: rpmTagEnhanceName, Version: rpmTagEnhanceVersion, Flags: rpmTagEnhanceFlags},
+	{Type: "replaces", Name: rpmTagObsoleteName, Version: rpmTagObsoleteVersion, Flags: rpmTagObsoleteFlags},
+}
+
+func readDependencies(strs map[int32][]string, ints map[int32][]int64) []packfile.Dependency {
+	var list []packfile.Dependency
+	for _, d := range rpmDependencies {
+		var (
+			names    = strs[d.Name]
+			versions = strs[d.Version]
+			flags    = ints[d.Flags]
+		)
+		for i := range names {
+			dep := packfile.Dependency{
+				Package: names[i],
+				Type:    d.Type,
+			}
+			if i < len(versions) && versions[i] != "" {
+				dep.Version = versions[i]
+				if i < len(flags) {
+					dep.Constraint = getDependencyConstraint(flags[i])
+				}
+			}
+			list = append(list, dep)
+		}
+	}
+	return list
 }
 
-func Dependencies(file string) ([]string, error) {
-	return nil, nil
+func getDependencyConstraint(flag int64) string {
+	switch flag & (rpmFlagDependsLess | rpmFlagDependsGreater | rpmFlagDependsEqual) {
+	case rpmFlagDependsEqual:
+		return packfile.ConstraintEq
+	case rpmFlagDependsGreater:
+		return packfile.ConstraintGt
+	case rpmFlagDependsGreater | rpmFlagDependsEqual:
+		return packfile.ConstraintGe
+	case rpmFlagDependsLess:
+		return packfile.ConstraintLt
+	case rpmFlagDependsLess | rpmFlagDependsEqual:
+		return packfile.ConstraintLe
+	default:
+		return ""
+	}
 }
 
 func readPackage(index io.Reader, store io.ReadSeeker, total int) (*PackageInfo, error) {
-	var pkg PackageInfo
+	var (
+		pkg     PackageInfo
+		files   rpmFiles
+		changes rpmChanges
+		strs    = make(map[int32][]string)
+		ints    = make(map[int32][]int64)
+	)
 
 	for i := 0; i < total; i++ {
 		var (
@@ -105,7 +322,9 @@ func readPackage(index io.Reader, store io.ReadSeeker, total int) (*PackageInfo,
 		binary.Read(index, binary.BigEndian, &offset)
 		binary.Read(index, binary.BigEndian, &count)
 
-		store.Seek(int64(offset), io.SeekStart)
+		if _, err := store.Seek(int64(offset), io.SeekStart); err != nil {
+			return nil, err
+		}
 
 		var err error
 		switch tag {
@@ -115,6 +334,10 @@ func readPackage(index io.Reader, store io.ReadSeeker, total int) (*PackageInfo,
 			pkg.Version, err = readString(store)
 		case rpmTagRelease:
 			pkg.Release, err = readString(store)
+		case rpmTagEpoch:
+			pkg.Epoch, err = readInt(store)
+		case rpmTagSourceRpm:
+			pkg.SourceRpm, err = readString(store)
 		case rpmTagSummary:
 			pkg.Summary, err = readString(store)
 		case rpmTagDesc:
@@ -126,11 +349,16 @@ func readPackage(index io.Reader, store io.ReadSeeker, total int) (*PackageInfo,
 		case rpmTagLicense:
 			pkg.License, err = readString(store)
 		case rpmTagPackager:
-			pkg.Maintainer.Name, err = readString(store)
+			var str string
+			if str, err = readString(store); err == nil {
+				pkg.Maintainer = packfile.ParseMaintainer(str)
+			}
 		case rpmTagGroup:
 			pkg.Section, err = readString(store)
 		case rpmTagURL:
 			pkg.Home, err = readString(store)
+		case rpmTagOS:
+			pkg.Os, err = readString(store)
 		case rpmTagArch:
 			pkg.Arch, err = readString(store)
 		case rpmTagBuildTime:
@@ -139,12 +367,60 @@ func readPackage(index io.Reader, store io.ReadSeeker, total int) (*PackageInfo,
 			pkg.BuildHost, err = readString(store)
 		case rpmTagSize:
 			pkg.Size, err = readInt(store)
+		case rpmTagArchiveSize:
+			pkg.ArchiveSize, err = readInt(store)
+		case rpmTagCompressor:
+			pkg.Compressor, err = readString(store)
+		case rpmTagPrein:
+			pkg.PreInst, err = readString(store)
+		case rpmTagPostin:
+			pkg.PostInst, err = readString(store)
+		case rpmTagPreun:
+			pkg.PreRem, err = readString(store)
+		case rpmTagPostun:
+			pkg.PostRem, err = readString(store)
+		case rpmTagCheckScript:
+			pkg.CheckScript, err = readString(store)
+		case rpmTagChangeTime:
+			changes.times, err = readIntArray(store, kind, count)
+		case rpmTagChangeName:
+			changes.names, err = readStringArray(store, count)
+		case rpmTagChangeText:
+			changes.texts, err = readStringArray(store, count)
+		case rpmTagFileSizes:
+			files.sizes, err = readIntArray(store, kind, count)
+		case rpmTagFileModes:
+			files.modes, err = readIntArray(store, kind, count)
+		case rpmTagFileTimes:
+			files.times, err = readIntArray(store, kind, count)
+		case rpmTagFileFlags:
+			files.flags, err = readIntArray(store, kind, count)
+		case rpmTagDirIndexes:
+			files.indexes, err = readIntArray(store, kind, count)
+		case rpmTagFileDigests:
+			files.digests, err = readStringArray(store, count)
+		case rpmTagBasenames:
+			files.bases, err = readStringArray(store, count)
+		case rpmTagDirnames:
+			files.dirs, err = readStringArray(store, count)
+		case rpmTagRequireName, rpmTagRequireVersion, rpmTagProvideName, rpmTagProvideVersion,
+			rpmTagConflictName, rpmTagConflictVersion, rpmTagRecommendName, rpmTagRecommendVersion,
+			rpmTagSuggestName, rpmTagSuggestVersion, rpmTagEnhanceName, rpmTagEnhanceVersion,
+			rpmTagObsoleteName, rpmTagObsoleteVersion:
+			strs[tag], err = readStringArray(store, count)
+		case rpmTagRequireFlags, rpmTagProvideFlags, rpmTagConflictFlags,
+			rpmTagRecommendFlags, rpmTagSuggestFlags, rpmTagEnhanceFlags, rpmTagObsoleteFlags:
+			ints[tag], err = readIntArray(store, kind, count)
 		default:
 		}
 		if err != nil {
 			return nil, err
 		}
 	}
+	pkg.Files = files.Resources()
+	pkg.Dirs = files.Directories()
+	pkg.Changes = changes.Changes()
+	pkg.Depends = readDependencies(strs, ints)
 
 	return &pkg, nil
 }
@@ -167,5 +443,54 @@ func readInt(r io.Reader) (int64, error) {
 
 func readString(r io.Reader) (string, error) {
 	tmp := bufio.NewReader(r)
-	return tmp.ReadString(0)
+	str, err := tmp.ReadString(0)
+	return strings.TrimSuffix(str, "\x00"), err
+}
+
+func readStringArray(r io.Reader, count int32) ([]string, error) {
+	var (
+		tmp  = bufio.NewReader(r)
+		list = make([]string, 0, count)
+	)
+	for i := 0; i < int(count); i++ {
+		str, err := tmp.ReadString(0)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, strings.TrimSuffix(str, "\x00"))
+	}
+	return list, nil
+}
+
+func readIntArray(r io.Reader, kind, count int32) ([]int64, error) {
+	list := make([]int64, 0, count)
+	for i := 0; i < int(count); i++ {
+		var (
+			val int64
+			err error
+		)
+		switch kind {
+		case fieldInt8:
+			var v uint8
+			err = binary.Read(r, binary.BigEndian, &v)
+			val = int64(v)
+		case fieldInt16:
+			var v uint16
+			err = binary.Read(r, binary.BigEndian, &v)
+			val = int64(v)
+		case fieldInt32:
+			var v uint32
+			err = binary.Read(r, binary.BigEndian, &v)
+			val = int64(v)
+		case fieldInt64:
+			err = binary.Read(r, binary.BigEndian, &val)
+		default:
+			return nil, fmt.Errorf("invalid type: only number type accepted")
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, val)
+	}
+	return list, nil
 }
@@ -0,0 +1,181 @@
+package rpm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/tape/cpio"
+)
+
+func Load(file string) (*packfile.Package, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	if err := readLead(r); err != nil {
+		return nil, err
+	}
+	if err := readHeader(r, io.Discard, io.Discard, true); err != nil {
+		return nil, err
+	}
+	var (
+		index bytes.Buffer
+		store bytes.Buffer
+	)
+	if err := readHeader(r, &index, &store, false); err != nil {
+		return nil, err
+	}
+	info, err := readPackage(&index, bytes.NewReader(store.Bytes()), index.Len()/rpmEntryLen)
+	if err != nil {
+		return nil, err
+	}
+	if info.Compressor != "" && info.Compressor != rpmPayloadCompressor {
+		return nil, fmt.Errorf("%s: payload compressor not supported", info.Compressor)
+	}
+	pkg := info.Package
+	if info.Epoch > 0 {
+		pkg.Version = fmt.Sprintf("%d:%s", info.Epoch, pkg.Version)
+	}
+	if err := loadFiles(r, &pkg); err != nil {
+		return nil, err
+	}
+	pkg.Files = slices.DeleteFunc(pkg.Files, func(r packfile.Resource) bool {
+		return r.Local == nil && !r.IsSymlink() && !r.IsDirectory()
+	})
+	pkg.Files = packfile.PruneDirs(pkg.Files)
+	return &pkg, nil
+}
+
+func loadFiles(r io.Reader, pkg *packfile.Package) error {
+	z, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	cp := cpio.NewReader(z)
+	for {
+		h, err := cp.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		var tmp bytes.Buffer
+		if _, err := io.CopyN(&tmp, cp, h.Size); err != nil {
+			return err
+		}
+		target := strings.TrimPrefix(strings.TrimPrefix(h.Filename, "."), "/")
+		if h.Mode&int64(os.ModeDir) != 0 || h.Mode&rpmFileTypeMask == rpmFileTypeDir {
+			pkg.Files = append(pkg.Files, packfile.Resource{
+				Path:    target,
+				Target:  target,
+				Perm:    h.Mode & 0o7777,
+				Flags:   packfile.FileFlagDir,
+				Lastmod: h.ModTime,
+			})
+			continue
+		}
+		ix := slices.IndexFunc(pkg.Files, func(r packfile.Resource) bool {
+			return r.Target == target
+		})
+		if ix < 0 {
+			continue
+		}
+		switch h.Mode & rpmFileTypeMask {
+		case rpmFileTypeReg:
+			pkg.Files[ix].Local = io.NopCloser(&tmp)
+			pkg.Files[ix].Size = h.Size
+		case rpmFileTypeLink:
+			pkg.Files[ix].Link = tmp.String()
+			pkg.Files[ix].Size = 0
+		default:
+		}
+	}
+	return nil
+}
+
+func Payload(file string, fn func(*tar.Header, io.Reader) error) error {
+	r, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := readLead(r); err != nil {
+		return err
+	}
+	if err := readHeader(r, io.Discard, io.Discard, true); err != nil {
+		return err
+	}
+	var (
+		index bytes.Buffer
+		store bytes.Buffer
+	)
+	if err := readHeader(r, &index, &store, false); err != nil {
+		return err
+	}
+	info, err := readPackage(&index, bytes.NewReader(store.Bytes()), index.Len()/rpmEntryLen)
+	if err != nil {
+		return err
+	}
+	if info.Compressor != "" && info.Compressor != rpmPayloadCompressor {
+		return fmt.Errorf("%s: payload compressor not supported", info.Compressor)
+	}
+	z, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	cp := cpio.NewReader(z)
+	for {
+		h, err := cp.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		var (
+			body = io.LimitReader(cp, h.Size)
+			hdr  = tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     strings.TrimPrefix(h.Filename, "."),
+				Mode:     h.Mode & 0o7777,
+				Uid:      int(h.Uid),
+				Gid:      int(h.Gid),
+				Size:     h.Size,
+				ModTime:  h.ModTime,
+			}
+		)
+		switch {
+		case h.Mode&int64(os.ModeDir) != 0 || h.Mode&rpmFileTypeMask == rpmFileTypeDir:
+			hdr.Typeflag = tar.TypeDir
+			hdr.Size = 0
+		case h.Mode&rpmFileTypeMask == rpmFileTypeLink:
+			var link bytes.Buffer
+			if _, err := io.Copy(&link, body); err != nil {
+				return err
+			}
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = link.String()
+			hdr.Size = 0
+		default:
+		}
+		if err := fn(&hdr, body); err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,535 @@
+package rpm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/pgp"
+)
+
+const (
+	repodataDir   = "repodata"
+	repomdFile    = "repomd.xml"
+	repomdSigFile = "repomd.xml.asc"
+)
+
+const (
+	nsCommon    = "http://linux.duke.edu/metadata/common"
+	nsRpm       = "http://linux.duke.edu/metadata/rpm"
+	nsFilelists = "http://linux.duke.edu/metadata/filelists"
+	nsOther     = "http://linux.duke.edu/metadata/other"
+	nsRepo      = "http://linux.duke.edu/metadata/repo"
+)
+
+type repoEntry struct {
+	*PackageInfo
+	Location string
+	Checksum string
+	Length   int64
+	Lastmod  int64
+	Start    int64
+	End      int64
+}
+
+type repoVersion struct {
+	Epoch   string `xml:"epoch,attr"`
+	Version string `xml:"ver,attr"`
+	Release string `xml:"rel,attr"`
+}
+
+type repoChecksum struct {
+	Type  string `xml:"type,attr"`
+	PkgId string `xml:"pkgid,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type repoEntryDep struct {
+	Name    string `xml:"name,attr"`
+	Flags   string `xml:"flags,attr,omitempty"`
+	Epoch   string `xml:"epoch,attr,omitempty"`
+	Version string `xml:"ver,attr,omitempty"`
+	Release string `xml:"rel,attr,omitempty"`
+}
+
+type repoDeps struct {
+	Entries []repoEntryDep `xml:"rpm:entry"`
+}
+
+type repoFile struct {
+	Type string `xml:"type,attr,omitempty"`
+	Name string `xml:",chardata"`
+}
+
+type primaryPackage struct {
+	Type        string       `xml:"type,attr"`
+	Name        string       `xml:"name"`
+	Arch        string       `xml:"arch"`
+	Version     repoVersion  `xml:"version"`
+	Checksum    repoChecksum `xml:"checksum"`
+	Summary     string       `xml:"summary"`
+	Description string       `xml:"description"`
+	Packager    string       `xml:"packager"`
+	URL         string       `xml:"url"`
+	Time        struct {
+		File  int64 `xml:"file,attr"`
+		Build int64 `xml:"build,attr"`
+	} `xml:"time"`
+	Size struct {
+		Package   int64 `xml:"package,attr"`
+		Installed int64 `xml:"installed,attr"`
+		Archive   int64 `xml:"archive,attr"`
+	} `xml:"size"`
+	Location struct {
+		Href string `xml:"href,attr"`
+	} `xml:"location"`
+	Format struct {
+		License     string `xml:"rpm:license"`
+		Vendor      string `xml:"rpm:vendor"`
+		Group       string `xml:"rpm:group"`
+		BuildHost   string `xml:"rpm:buildhost"`
+		SourceRpm   string `xml:"rpm:sourcerpm"`
+		HeaderRange struct {
+			Start int64 `xml:"start,attr"`
+			End   int64 `xml:"end,attr"`
+		} `xml:"rpm:header-range"`
+		Provides   *repoDeps  `xml:"rpm:provides,omitempty"`
+		Requires   *repoDeps  `xml:"rpm:requires,omitempty"`
+		Conflicts  *repoDeps  `xml:"rpm:conflicts,omitempty"`
+		Obsoletes  *repoDeps  `xml:"rpm:obsoletes,omitempty"`
+		Suggests   *repoDeps  `xml:"rpm:suggests,omitempty"`
+		Recommends *repoDeps  `xml:"rpm:recommends,omitempty"`
+		Enhances   *repoDeps  `xml:"rpm:enhances,omitempty"`
+		Files      []repoFile `xml:"file"`
+	} `xml:"format"`
+}
+
+type primaryMetadata struct {
+	XMLName  xml.Name         `xml:"metadata"`
+	NS       string           `xml:"xmlns,attr"`
+	NSRpm    string           `xml:"xmlns:rpm,attr"`
+	Count    int              `xml:"packages,attr"`
+	Packages []primaryPackage `xml:"package"`
+}
+
+type filelistsPackage struct {
+	PkgId   string      `xml:"pkgid,attr"`
+	Name    string      `xml:"name,attr"`
+	Arch    string      `xml:"arch,attr"`
+	Version repoVersion `xml:"version"`
+	Files   []repoFile  `xml:"file"`
+}
+
+type filelistsMetadata struct {
+	XMLName  xml.Name           `xml:"filelists"`
+	NS       string             `xml:"xmlns,attr"`
+	Count    int                `xml:"packages,attr"`
+	Packages []filelistsPackage `xml:"package"`
+}
+
+type repoChange struct {
+	Author string `xml:"author,attr"`
+	Date   int64  `xml:"date,attr"`
+	Text   string `xml:",chardata"`
+}
+
+type otherPackage struct {
+	PkgId   string       `xml:"pkgid,attr"`
+	Name    string       `xml:"name,attr"`
+	Arch    string       `xml:"arch,attr"`
+	Version repoVersion  `xml:"version"`
+	Changes []repoChange `xml:"changelog"`
+}
+
+type otherMetadata struct {
+	XMLName  xml.Name       `xml:"otherdata"`
+	NS       string         `xml:"xmlns,attr"`
+	Count    int            `xml:"packages,attr"`
+	Packages []otherPackage `xml:"package"`
+}
+
+type repomdData struct {
+	Type         string       `xml:"type,attr"`
+	Checksum     repoChecksum `xml:"checksum"`
+	OpenChecksum repoChecksum `xml:"open-checksum"`
+	Location     struct {
+		Href string `xml:"href,attr"`
+	} `xml:"location"`
+	Timestamp int64 `xml:"timestamp"`
+	Size      int   `xml:"size"`
+	OpenSize  int   `xml:"open-size"`
+}
+
+type repomd struct {
+	XMLName  xml.Name     `xml:"repomd"`
+	NS       string       `xml:"xmlns,attr"`
+	NSRpm    string       `xml:"xmlns:rpm,attr"`
+	Revision int64        `xml:"revision"`
+	Data     []repomdData `xml:"data"`
+}
+
+func WriteRepository(dir string, key *pgp.Key, when time.Time) error {
+	list, err := collectPackages(dir)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("%s: no rpm packages found", dir)
+	}
+	var (
+		primary   = primaryMetadata{NS: nsCommon, NSRpm: nsRpm, Count: len(list)}
+		filelists = filelistsMetadata{NS: nsFilelists, Count: len(list)}
+		other     = otherMetadata{NS: nsOther, Count: len(list)}
+	)
+	for _, e := range list {
+		primary.Packages = append(primary.Packages, makePrimaryPackage(e))
+		filelists.Packages = append(filelists.Packages, makeFilelistsPackage(e))
+		other.Packages = append(other.Packages, makeOtherPackage(e))
+	}
+	if err := os.MkdirAll(filepath.Join(dir, repodataDir), 0o755); err != nil {
+		return err
+	}
+	var (
+		md = repomd{NS: nsRepo, NSRpm: nsRpm, Revision: when.Unix()}
+	)
+	for _, d := range []struct {
+		Type string
+		Data any
+	}{
+		{Type: "primary", Data: primary},
+		{Type: "filelists", Data: filelists},
+		{Type: "other", Data: other},
+	} {
+		data, err := writeRepoFile(dir, d.Type, d.Data, when)
+		if err != nil {
+			return err
+		}
+		data.Timestamp = when.Unix()
+		md.Data = append(md.Data, *data)
+	}
+	body, err := marshalXML(md)
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		sig, err := key.ArmoredSign(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if err := packfile.WriteFile(filepath.Join(dir, repodataDir, repomdSigFile), sig); err != nil {
+			return err
+		}
+	}
+	return packfile.WriteFile(filepath.Join(dir, repodataDir, repomdFile), body)
+}
+
+func writeRepoFile(dir, kind string, data any, when time.Time) (*repomdData, error) {
+	plain, err := marshalXML(data)
+	if err != nil {
+		return nil, err
+	}
+	var zip bytes.Buffer
+	z, _ := gzip.NewWriterLevel(&zip, gzip.BestCompression)
+	z.ModTime = when
+	if _, err := z.Write(plain); err != nil {
+		return nil, err
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	var (
+		file = kind + ".xml.gz"
+		md   repomdData
+	)
+	if err := packfile.WriteFile(filepath.Join(dir, repodataDir, file), zip.Bytes()); err != nil {
+		return nil, err
+	}
+	md.Type = kind
+	md.Checksum = makeRepoChecksum(zip.Bytes())
+	md.OpenChecksum = makeRepoChecksum(plain)
+	md.Location.Href = repodataDir + "/" + file
+	md.Size = zip.Len()
+	md.OpenSize = len(plain)
+	return &md, nil
+}
+
+func marshalXML(data any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	e := xml.NewEncoder(&buf)
+	e.Indent("", "  ")
+	if err := e.Encode(data); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+func makeRepoChecksum(body []byte) repoChecksum {
+	sum := sha256.Sum256(body)
+	return repoChecksum{
+		Type:  "sha256",
+		Value: hex.EncodeToString(sum[:]),
+	}
+}
+
+func collectPackages(dir string) ([]*repoEntry, error) {
+	var list []*repoEntry
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(file) != ".rpm" {
+			return nil
+		}
+		e, err := readRepoEntry(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if e.Location, err = filepath.Rel(dir, file); err != nil {
+			return err
+		}
+		e.Location = filepath.ToSlash(e.Location)
+		list = append(list, e)
+		return nil
+	})
+	slices.SortFunc(list, func(a, b *repoEntry) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Location, b.Location)
+	})
+	return list, err
+}
+
+func readRepoEntry(file string) (*repoEntry, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var e repoEntry
+	if err := readLead(r); err != nil {
+		return nil, err
+	}
+	var (
+		sigIndex bytes.Buffer
+		sigStore bytes.Buffer
+	)
+	if err := readHeader(r, &sigIndex, &sigStore, true); err != nil {
+		return nil, err
+	}
+	if e.Start, err = r.Seek(0, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+	var (
+		index bytes.Buffer
+		store bytes.Buffer
+	)
+	if err := readHeader(r, &index, &store, false); err != nil {
+		return nil, err
+	}
+	if e.End, err = r.Seek(0, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+	if e.PackageInfo, err = readPackage(&index, bytes.NewReader(store.Bytes()), index.Len()/rpmEntryLen); err != nil {
+		return nil, err
+	}
+	if e.ArchiveSize == 0 {
+		if e.ArchiveSize, err = readPayloadSize(&sigIndex, sigStore.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	sum := sha256.New()
+	if e.Length, err = io.Copy(sum, r); err != nil {
+		return nil, err
+	}
+	e.Checksum = hex.EncodeToString(sum.Sum(nil))
+
+	s, err := r.Stat()
+	if err != nil {
+		return nil, err
+	}
+	e.Lastmod = s.ModTime().Unix()
+	return &e, nil
+}
+
+func makePrimaryPackage(e *repoEntry) primaryPackage {
+	var pkg primaryPackage
+	pkg.Type = "rpm"
+	pkg.Name = e.Name
+	pkg.Arch = e.Arch
+	pkg.Version = makeRepoVersion(e)
+	pkg.Checksum = repoChecksum{
+		Type:  "sha256",
+		PkgId: "YES",
+		Value: e.Checksum,
+	}
+	pkg.Summary = e.Summary
+	pkg.Description = e.Desc
+	pkg.Packager = e.Maintainer.String()
+	pkg.URL = e.Home
+	pkg.Time.File = e.Lastmod
+	pkg.Time.Build = e.BuildTime.Unix()
+	pkg.Size.Package = e.Length
+	pkg.Size.Installed = e.Size
+	pkg.Size.Archive = e.ArchiveSize
+	pkg.Location.Href = e.Location
+
+	pkg.Format.License = e.License
+	pkg.Format.Vendor = e.Vendor
+	pkg.Format.Group = e.Section
+	pkg.Format.BuildHost = e.BuildHost
+	pkg.Format.SourceRpm = e.SourceRpm
+	pkg.Format.HeaderRange.Start = e.Start
+	pkg.Format.HeaderRange.End = e.End
+
+	pkg.Format.Provides = makeRepoDeps(e.Depends, "provides")
+	pkg.Format.Requires = makeRepoDeps(e.Depends, "depends")
+	pkg.Format.Conflicts = makeRepoDeps(e.Depends, "conflicts")
+	pkg.Format.Obsoletes = makeRepoDeps(e.Depends, "replaces")
+	pkg.Format.Suggests = makeRepoDeps(e.Depends, "suggests")
+	pkg.Format.Recommends = makeRepoDeps(e.Depends, "recommends")
+	pkg.Format.Enhances = makeRepoDeps(e.Depends, "enhances")
+
+	for _, f := range getRepoFiles(e) {
+		if isPrimaryFile(f.Name) {
+			pkg.Format.Files = append(pkg.Format.Files, f)
+		}
+	}
+	return pkg
+}
+
+func makeFilelistsPackage(e *repoEntry) filelistsPackage {
+	return filelistsPackage{
+		PkgId:   e.Checksum,
+		Name:    e.Name,
+		Arch:    e.Arch,
+		Version: makeRepoVersion(e),
+		Files:   getRepoFiles(e),
+	}
+}
+
+func makeOtherPackage(e *repoEntry) otherPackage {
+	pkg := otherPackage{
+		PkgId:   e.Checksum,
+		Name:    e.Name,
+		Arch:    e.Arch,
+		Version: makeRepoVersion(e),
+	}
+	for _, c := range e.Changes {
+		author := c.Maintainer.String()
+		if c.Version != "" {
+			author = fmt.Sprintf("%s - %s", author, c.Version)
+		}
+		var lines []string
+		if c.Summary != "" {
+			lines = append(lines, c.Summary)
+		}
+		for _, str := range c.Changes {
+			lines = append(lines, "- "+str)
+		}
+		pkg.Changes = append(pkg.Changes, repoChange{
+			Author: author,
+			Date:   c.When.Unix(),
+			Text:   strings.Join(lines, "\n"),
+		})
+	}
+	return pkg
+}
+
+func makeRepoVersion(e *repoEntry) repoVersion {
+	return repoVersion{
+		Epoch:   strconv.FormatInt(e.Epoch, 10),
+		Version: e.Version,
+		Release: e.Release,
+	}
+}
+
+func makeRepoDeps(list []packfile.Dependency, kind string) *repoDeps {
+	var deps repoDeps
+	for _, d := range list {
+		if d.Type != kind || strings.HasPrefix(d.Package, "rpmlib(") {
+			continue
+		}
+		entry := repoEntryDep{
+			Name: d.Package,
+		}
+		if d.Version != "" {
+			entry.Flags = getRepoFlag(d.Constraint)
+			entry.Epoch, entry.Version, entry.Release = splitRepoVersion(d.Version)
+		}
+		deps.Entries = append(deps.Entries, entry)
+	}
+	if len(deps.Entries) == 0 {
+		return nil
+	}
+	return &deps
+}
+
+func getRepoFiles(e *repoEntry) []repoFile {
+	var list []repoFile
+	for _, d := range e.Dirs {
+		list = append(list, repoFile{Type: "dir", Name: "/" + d})
+	}
+	for _, r := range e.Files {
+		f := repoFile{Name: "/" + r.Target}
+		if r.Flags&rpmFileGhost != 0 {
+			f.Type = "ghost"
+		}
+		list = append(list, f)
+	}
+	slices.SortFunc(list, func(a, b repoFile) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return list
+}
+
+func isPrimaryFile(file string) bool {
+	return strings.HasPrefix(file, "/etc/") || strings.Contains(file, "bin/") || file == "/usr/lib/sendmail"
+}
+
+func splitRepoVersion(str string) (string, string, string) {
+	epoch := "0"
+	if e, rest, ok := strings.Cut(str, ":"); ok {
+		epoch, str = e, rest
+	}
+	var rel string
+	if ix := strings.LastIndex(str, "-"); ix > 0 {
+		str, rel = str[:ix], str[ix+1:]
+	}
+	return epoch, str, rel
+}
+
+func getRepoFlag(constraint string) string {
+	switch constraint {
+	case packfile.ConstraintEq:
+		return "EQ"
+	case packfile.ConstraintGt:
+		return "GT"
+	case packfile.ConstraintGe:
+		return "GE"
+	case packfile.ConstraintLt:
+		return "LT"
+	case packfile.ConstraintLe:
+		return "LE"
+	default:
+		return ""
+	}
+}
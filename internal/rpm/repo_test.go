@@ -0,0 +1,159 @@
+package rpm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+func TestMakePrimaryPackage(t *testing.T) {
+	tests := []struct {
+		name      string
+		info      PackageInfo
+		version   repoVersion
+		requires  *repoDeps
+		obsoletes *repoDeps
+		files     []repoFile
+	}{
+		{
+			name: "minimal",
+			info: PackageInfo{
+				Package: packfile.Package{Name: "foo", Version: "1.0", Release: "1"},
+			},
+			version: repoVersion{Epoch: "0", Version: "1.0", Release: "1"},
+		},
+		{
+			name: "dependencies",
+			info: PackageInfo{
+				Package: packfile.Package{
+					Name:    "foo",
+					Version: "1.0",
+					Release: "2",
+					Depends: []packfile.Dependency{
+						{Package: "rpmlib(CompressedFileNames)", Constraint: packfile.ConstraintLe, Version: "3.0.4-1", Type: "depends"},
+						{Package: "libc.so.6()(64bit)", Type: "depends"},
+						{Package: "bar", Constraint: packfile.ConstraintGe, Version: "1:2.0-3", Type: "depends"},
+						{Package: "baz", Constraint: packfile.ConstraintLt, Version: "0.9", Type: "replaces"},
+					},
+				},
+				Epoch: 2,
+			},
+			version: repoVersion{Epoch: "2", Version: "1.0", Release: "2"},
+			requires: &repoDeps{
+				Entries: []repoEntryDep{
+					{Name: "libc.so.6()(64bit)"},
+					{Name: "bar", Flags: "GE", Epoch: "1", Version: "2.0", Release: "3"},
+				},
+			},
+			obsoletes: &repoDeps{
+				Entries: []repoEntryDep{
+					{Name: "baz", Flags: "LT", Epoch: "0", Version: "0.9"},
+				},
+			},
+		},
+		{
+			name: "files",
+			info: PackageInfo{
+				Package: packfile.Package{
+					Name:    "foo",
+					Version: "1.0",
+					Files: []packfile.Resource{
+						{Target: "usr/share/doc/foo/README"},
+						{Target: "usr/bin/foo"},
+						{Target: "etc/foo.conf"},
+						{Target: "var/log/foo.log", Flags: rpmFileGhost},
+						{Target: "etc/foo.d/ghost", Flags: rpmFileGhost},
+					},
+				},
+				Dirs: []string{"etc/foo.d", "usr/share/doc/foo"},
+			},
+			version: repoVersion{Epoch: "0", Version: "1.0"},
+			files: []repoFile{
+				{Name: "/etc/foo.conf"},
+				{Name: "/etc/foo.d", Type: "dir"},
+				{Name: "/etc/foo.d/ghost", Type: "ghost"},
+				{Name: "/usr/bin/foo"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		e := repoEntry{
+			PackageInfo: &tt.info,
+			Location:    "foo.rpm",
+		}
+		pkg := makePrimaryPackage(&e)
+		if pkg.Name != tt.info.Name || pkg.Location.Href != e.Location {
+			t.Errorf("%s: name/location mismatched (%s, %s)", tt.name, pkg.Name, pkg.Location.Href)
+		}
+		if pkg.Version != tt.version {
+			t.Errorf("%s: want version %+v, got %+v", tt.name, tt.version, pkg.Version)
+		}
+		if !reflect.DeepEqual(pkg.Format.Requires, tt.requires) {
+			t.Errorf("%s: want requires %+v, got %+v", tt.name, tt.requires, pkg.Format.Requires)
+		}
+		if !reflect.DeepEqual(pkg.Format.Obsoletes, tt.obsoletes) {
+			t.Errorf("%s: want obsoletes %+v, got %+v", tt.name, tt.obsoletes, pkg.Format.Obsoletes)
+		}
+		if !reflect.DeepEqual(pkg.Format.Files, tt.files) {
+			t.Errorf("%s: want files %+v, got %+v", tt.name, tt.files, pkg.Format.Files)
+		}
+	}
+}
+
+func TestMarshalPrimary(t *testing.T) {
+	info := PackageInfo{
+		Package: packfile.Package{
+			Name:       "foo",
+			Version:    "1.0",
+			Release:    "1",
+			Arch:       "x86_64",
+			Summary:    "foo & bar",
+			Maintainer: packfile.Maintainer{Name: "foo", Email: "foo@example.org"},
+			Depends: []packfile.Dependency{
+				{Package: "bar", Constraint: packfile.ConstraintEq, Version: "2.0", Type: "depends"},
+			},
+			Files: []packfile.Resource{
+				{Target: "usr/bin/foo"},
+			},
+		},
+		BuildTime: time.Unix(100, 0),
+	}
+	e := repoEntry{
+		PackageInfo: &info,
+		Location:    "foo-1.0-1.x86_64.rpm",
+		Checksum:    "abcd",
+		Lastmod:     200,
+	}
+	data, err := marshalXML(primaryMetadata{
+		NS:       nsCommon,
+		NSRpm:    nsRpm,
+		Count:    1,
+		Packages: []primaryPackage{makePrimaryPackage(&e)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{
+		`<metadata xmlns="http://linux.duke.edu/metadata/common" xmlns:rpm="http://linux.duke.edu/metadata/rpm" packages="1">`,
+		`<package type="rpm">`,
+		`<version epoch="0" ver="1.0" rel="1"></version>`,
+		`<checksum type="sha256" pkgid="YES">abcd</checksum>`,
+		`<summary>foo &amp; bar</summary>`,
+		`<packager>foo &lt;foo@example.org&gt;</packager>`,
+		`<time file="200" build="100"></time>`,
+		`<location href="foo-1.0-1.x86_64.rpm"></location>`,
+		`<rpm:entry name="bar" flags="EQ" epoch="0" ver="2.0"></rpm:entry>`,
+		`<file>/usr/bin/foo</file>`,
+	}
+	for _, str := range want {
+		if !strings.Contains(string(data), str) {
+			t.Errorf("%s not found in primary.xml", str)
+		}
+	}
+	if strings.Contains(string(data), "rpm:obsoletes") {
+		t.Errorf("empty obsoletes written in primary.xml")
+	}
+}
@@ -17,10 +17,12 @@ import (
 	"path"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/pgp"
 	"github.com/midbel/tape"
 	"github.com/midbel/tape/cpio"
 )
@@ -29,6 +31,8 @@ type RpmBuilder struct {
 	writer    *bufio.Writer
 	buildTime time.Time
 	buildHost string
+
+	key *pgp.Key
 }
 
 func Build(w io.Writer) (*RpmBuilder, error) {
@@ -53,6 +57,20 @@ func (b *RpmBuilder) Build(p *packfile.Package) error {
 	return b.teardown(p)
 }
 
+func (b *RpmBuilder) SetSourceDateEpoch(when time.Time) {
+	b.buildTime = when
+	b.buildHost = "localhost"
+}
+
+func (b *RpmBuilder) SignWith(file, _ string) error {
+	key, err := pgp.ReadPrivateKey(file)
+	if err != nil {
+		return err
+	}
+	b.key = key
+	return nil
+}
+
 func (b *RpmBuilder) Close() error {
 	return b.writer.Flush()
 }
@@ -86,14 +104,17 @@ func (b *RpmBuilder) teardown(pkg *packfile.Package) error {
 }
 
 func (b *RpmBuilder) build(p *packfile.Package) error {
-	data, err := writeFiles(p)
+	dir, err := os.MkdirTemp("", "packit-rpm-*")
 	if err != nil {
 		return err
 	}
-	defer func() {
-		data.Close()
-		os.Remove(data.Name())
-	}()
+	defer os.RemoveAll(dir)
+
+	data, err := writeFiles(p, dir, b.buildTime)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
 
 	if err := b.writeLead(p); err != nil {
 		return err
@@ -110,7 +131,9 @@ func (b *RpmBuilder) build(p *packfile.Package) error {
 		md = md5.New()
 		by = hdr.Bytes()
 	)
-	data.Seek(0, io.SeekStart)
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
 	totalSize, err := io.Copy(md, io.MultiReader(bytes.NewReader(by), data))
 	if err != nil {
 		return err
@@ -120,24 +143,79 @@ func (b *RpmBuilder) build(p *packfile.Package) error {
 	if err != nil {
 		return err
 	}
-	if err := b.writeSignatures(stat.Size(), totalSize, md, sh1, sh2); err != nil {
+	var sigs []rpmPgpSignature
+	if b.key != nil {
+		if _, err := data.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if sigs, err = b.sign(by, data); err != nil {
+			return err
+		}
+	}
+	if err := b.writeSignatures(stat.Size(), totalSize, md, sh1, sh2, sigs); err != nil {
+		return err
+	}
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
 		return err
 	}
-	data.Seek(0, io.SeekStart)
 	_, err = io.Copy(b.writer, io.MultiReader(bytes.NewReader(by), data))
 	return err
 }
 
-func (b *RpmBuilder) writeSignatures(data, total int64, md, sh1, sh2 hash.Hash) error {
+type rpmPgpSignature struct {
+	Tag  int32
+	Data []byte
+}
+
+func (b *RpmBuilder) sign(hdr []byte, data io.Reader) ([]rpmPgpSignature, error) {
+	var (
+		hdrTag int32 = rpmSigDSA
+		allTag int32 = rpmSigGPG
+	)
+	if b.key.IsRSA() {
+		hdrTag, allTag = rpmSigRSA, rpmSigPGP
+	}
+	sig, err := b.key.Sign(bytes.NewReader(hdr))
+	if err != nil {
+		return nil, err
+	}
+	list := []rpmPgpSignature{
+		{
+			Tag:  hdrTag,
+			Data: sig,
+		},
+	}
+	if sig, err = b.key.Sign(io.MultiReader(bytes.NewReader(hdr), data)); err != nil {
+		return nil, err
+	}
+	list = append(list, rpmPgpSignature{
+		Tag:  allTag,
+		Data: sig,
+	})
+	return list, nil
+}
+
+func (b *RpmBuilder) writeSignatures(data, total int64, md, sh1, sh2 hash.Hash, sigs []rpmPgpSignature) error {
 	var (
 		index bytes.Buffer
 		store bytes.Buffer
 	)
 
+	writeSig := func(from, to int32) {
+		for _, s := range sigs {
+			if s.Tag >= from && s.Tag < to {
+				writeBinaryEntry(&index, &store, s.Tag, fieldBinary, s.Data)
+			}
+		}
+	}
+
+	writeSig(0, rpmSigSha1)
 	writeHashString(&index, &store, rpmSigSha1, fieldString, sh1)
 	writeHashString(&index, &store, rpmSigSha256, fieldString, sh2)
 	writeIntEntry(&index, &store, rpmSigLength, fieldInt32, total)
+	writeSig(rpmSigLength+1, rpmSigMD5)
 	writeHashBinary(&index, &store, rpmSigMD5, fieldBinary, md)
+	writeSig(rpmSigMD5+1, rpmSigPayload)
 	writeIntEntry(&index, &store, rpmSigPayload, fieldInt32, data)
 
 	var (
@@ -174,9 +252,21 @@ func (b *RpmBuilder) prepareHeader(p *packfile.Package, ws io.Writer) error {
 		index bytes.Buffer
 		store bytes.Buffer
 	)
+	var (
+		version = p.Version
+		epoch   int64
+	)
+	if e, rest, ok := strings.Cut(version, ":"); ok {
+		if n, err := strconv.ParseInt(e, 10, 32); err == nil {
+			epoch, version = n, rest
+		}
+	}
 	writeStringEntry(&index, &store, rpmTagPackage, fieldString, p.Name)
-	writeStringEntry(&index, &store, rpmTagVersion, fieldString, p.Version)
+	writeStringEntry(&index, &store, rpmTagVersion, fieldString, version)
 	writeStringEntry(&index, &store, rpmTagRelease, fieldString, p.Release)
+	if epoch > 0 {
+		writeIntEntry(&index, &store, rpmTagEpoch, fieldInt32, epoch)
+	}
 	writeStringEntry(&index, &store, rpmTagSummary, fieldI18NString, p.Summary)
 	writeStringEntry(&index, &store, rpmTagDesc, fieldI18NString, p.Desc)
 	writeIntEntry(&index, &store, rpmTagBuildTime, fieldInt32, b.buildTime.Unix())
@@ -185,13 +275,13 @@ func (b *RpmBuilder) prepareHeader(p *packfile.Package, ws io.Writer) error {
 	writeStringEntry(&index, &store, rpmTagDistrib, fieldString, p.Distrib)
 	writeStringEntry(&index, &store, rpmTagVendor, fieldString, p.Vendor)
 	writeStringEntry(&index, &store, rpmTagLicense, fieldString, p.License)
-	writeStringEntry(&index, &store, rpmTagPackager, fieldString, p.Maintainer.Name)
+	writeStringEntry(&index, &store, rpmTagPackager, fieldString, p.Maintainer.String())
 	writeStringEntry(&index, &store, rpmTagGroup, fieldI18NString, p.Section)
 	writeStringEntry(&index, &store, rpmTagURL, fieldString, p.Home)
 	writeStringEntry(&index, &store, rpmTagArch, fieldString, p.Arch)
 
 	prepareChanges(p, &index, &store)
-	prepareFiles(p, &index, &store)
+	prepareFiles(p, &index, &store, b.buildTime)
 	prepareScripts(p, &index, &store)
 	prepareDependencies(p, &index, &store)
 
@@ -244,6 +334,14 @@ func (b *RpmBuilder) writeLead(p *packfile.Package) error {
 const (
 	fileBasePerm = -1 << 15
 	dirBasePerm  = 1 << 14
+	linkBasePerm = fileBasePerm + 1<<13
+)
+
+const (
+	rpmFileTypeMask = 0o170000
+	rpmFileTypeDir  = 0o040000
+	rpmFileTypeReg  = 0o100000
+	rpmFileTypeLink = 0o120000
 )
 
 func pathToSlash(str string) string {
@@ -257,7 +355,7 @@ func pathToRoot(str string) string {
 	return str
 }
 
-func prepareFiles(p *packfile.Package, index, store *bytes.Buffer) error {
+func prepareFiles(p *packfile.Package, index, store *bytes.Buffer, when time.Time) error {
 	var (
 		dirs    []string
 		bases   []string
@@ -273,7 +371,6 @@ func prepareFiles(p *packfile.Package, index, store *bytes.Buffer) error {
 		digests []string
 		links   []string
 		langs   []string
-		now     = time.Now()
 	)
 	for _, f := range p.Files {
 		f.Target = pathToSlash(f.Target)
@@ -295,7 +392,7 @@ func prepareFiles(p *packfile.Package, index, store *bytes.Buffer) error {
 			bases = append(bases, tmp)
 			perms = append(perms, dirBasePerm+f.Perm)
 			sizes = append(sizes, 0)
-			times = append(times, now.Unix())
+			times = append(times, when.Unix())
 			digests = append(digests, "")
 			users = append(users, packfile.DefaultUser)
 			groups = append(groups, packfile.DefaultGroup)
@@ -307,18 +404,28 @@ func prepareFiles(p *packfile.Package, index, store *bytes.Buffer) error {
 			langs = append(langs, "")
 		}
 
+		var (
+			perm = fileBasePerm + f.Perm
+			size = f.Size
+		)
+		switch {
+		case f.IsDirectory():
+			perm, size = dirBasePerm+f.Perm, 0
+		case f.IsSymlink():
+			perm, size = linkBasePerm+0o777, int64(len(f.Link))
+		}
 		indexes = append(indexes, int64(slices.Index(dirs, dir)))
 		bases = append(bases, base)
-		perms = append(perms, fileBasePerm+f.Perm)
-		sizes = append(sizes, f.Size)
-		times = append(times, now.Unix())
+		perms = append(perms, perm)
+		sizes = append(sizes, size)
+		times = append(times, f.Lastmod.Unix())
 		digests = append(digests, f.Hash)
 		users = append(users, packfile.DefaultUser)
 		groups = append(groups, packfile.DefaultGroup)
 		flags = append(flags, f.Flags)
 		devs = append(devs, 0)
 		inodes = append(inodes, int64(len(bases))+1)
-		links = append(links, "")
+		links = append(links, f.Link)
 		langs = append(langs, "")
 	}
 
@@ -361,9 +468,10 @@ func prepareDependencies(p *packfile.Package, index, store *bytes.Buffer) error
 	writeDeps(p.Provides(), rpmTagProvideName, rpmTagProvideVersion, rpmTagProvideFlags)
 	writeDeps(p.Requires(), rpmTagRequireName, rpmTagRequireVersion, rpmTagRequireFlags)
 	writeDeps(p.Conflicts(), rpmTagConflictName, rpmTagConflictVersion, rpmTagConflictFlags)
-	writeDeps(p.Enhances(), rpmTagEnhanceName, rpmTagEnhanceVersion, rpmTagEnhanceVersion)
+	writeDeps(p.Enhances(), rpmTagEnhanceName, rpmTagEnhanceVersion, rpmTagEnhanceFlags)
 	writeDeps(p.Recommends(), rpmTagRecommendName, rpmTagRecommendVersion, rpmTagRecommendFlags)
 	writeDeps(p.Suggests(), rpmTagSuggestName, rpmTagSuggestVersion, rpmTagSuggestFlags)
+	writeDeps(p.Replaces(), rpmTagObsoleteName, rpmTagObsoleteVersion, rpmTagObsoleteFlags)
 	return nil
 }
 
@@ -417,8 +525,8 @@ func prepareChanges(p *packfile.Package, index, store *bytes.Buffer) error {
 	return nil
 }
 
-func writeFiles(p *packfile.Package) (*os.File, error) {
-	f, err := os.Create(p.PackageName() + ".cpio.gz")
+func writeFiles(p *packfile.Package, tmpdir string, when time.Time) (*os.File, error) {
+	f, err := os.Create(filepath.Join(tmpdir, p.PackageName()+".cpio.gz"))
 	if err != nil {
 		return nil, err
 	}
@@ -457,7 +565,7 @@ func writeFiles(p *packfile.Package) (*os.File, error) {
 					Mode:     r.Perm | int64(os.ModeDir),
 					Uid:      0,
 					Gid:      0,
-					ModTime:  time.Now(),
+					ModTime:  when,
 				}
 				if err := cp.WriteHeader(&h); err != nil {
 					f.Close()
@@ -473,10 +581,32 @@ func writeFiles(p *packfile.Package) (*os.File, error) {
 			Gid:      0,
 			ModTime:  r.Lastmod,
 		}
+		switch {
+		case r.IsDirectory():
+			if _, ok := seen[r.Target]; ok {
+				continue
+			}
+			seen[r.Target] = struct{}{}
+			h.Mode |= int64(os.ModeDir)
+			h.Size = 0
+		case r.IsSymlink():
+			h.Mode = rpmFileTypeLink | 0o777
+			h.Size = int64(len(r.Link))
+		}
 		if err := cp.WriteHeader(&h); err != nil {
 			f.Close()
 			return nil, err
 		}
+		if r.IsDirectory() {
+			continue
+		}
+		if r.IsSymlink() {
+			if _, err := io.WriteString(cp, r.Link); err != nil {
+				f.Close()
+				return nil, err
+			}
+			continue
+		}
 		sum := md5.New()
 		if _, err := io.Copy(io.MultiWriter(cp, sum), r.Local); err != nil {
 			f.Close()
@@ -525,10 +655,11 @@ const (
 )
 
 const (
-	rpmSigBase = 256
-	// rpmSigPGP     = 1002
-	// rpmSigDSA     = rpmSigBase + 11
-	// rpmSigRSA     = rpmSigBase + 12
+	rpmSigBase    = 256
+	rpmSigPGP     = 1002
+	rpmSigGPG     = 1005
+	rpmSigDSA     = rpmSigBase + 11
+	rpmSigRSA     = rpmSigBase + 12
 	rpmSigSha1    = rpmSigBase + 13
 	rpmSigSha256  = rpmSigBase + 17
 	rpmSigLength  = 1000
@@ -540,6 +671,7 @@ const (
 	rpmTagPackage      = 1000
 	rpmTagVersion      = 1001
 	rpmTagRelease      = 1002
+	rpmTagEpoch        = 1003
 	rpmTagSummary      = 1004
 	rpmTagDesc         = 1005
 	rpmTagBuildTime    = 1006
@@ -553,6 +685,7 @@ const (
 	rpmTagURL          = 1020
 	rpmTagOS           = 1021
 	rpmTagArch         = 1022
+	rpmTagSourceRpm    = 1044
 	rpmTagPayload      = 1124
 	rpmTagCompressor   = 1125
 	rpmTagPayloadFlags = 1126
@@ -587,6 +720,7 @@ const (
 	rpmTagFileRequire    = 5002
 	rpmTagFileProvide    = 5001
 	rpmTagFileDigestAlgo = 5011
+	rpmTagLongFileSizes  = 5008
 )
 
 const (
@@ -7,6 +7,7 @@ import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
@@ -14,12 +15,15 @@ import (
 	"hash"
 	"io"
 	"os"
+	"path"
 	"strings"
 
+	"github.com/midbel/packit/internal/packfile"
+	"github.com/midbel/packit/internal/pgp"
 	"github.com/midbel/tape/cpio"
 )
 
-func Check(file string) error {
+func Check(file string, keyring *pgp.Keyring) error {
 	r, err := os.Open(file)
 	if err != nil {
 		return err
@@ -34,20 +38,31 @@ func Check(file string) error {
 	if err != nil {
 		return err
 	}
+	var (
+		hdrSum io.Writer = io.Discard
+		allSum io.Writer = io.Discard
+	)
+	hdrSig, allSig, err := sig.Verifiers(keyring)
+	if err != nil {
+		return err
+	}
+	if keyring != nil {
+		hdrSum, allSum = hdrSig, allSig
+	}
 	var (
 		totalCount counter
 		dataCount  counter
 		sh1        = sha1.New()
 		sh2        = sha256.New()
 		md         = md5.New()
-		sum        = io.MultiWriter(sh1, sh2, md, &totalCount)
+		sum        = io.MultiWriter(sh1, sh2, md, &totalCount, hdrSum, allSum)
 	)
-	_, err = readSums(io.TeeReader(r, sum))
+	digests, err := readSums(io.TeeReader(r, sum))
 	if err != nil {
 		return err
 	}
-	sum = io.MultiWriter(md, &totalCount, &dataCount)
-	if err := checkFiles(io.TeeReader(r, sum), nil); err != nil {
+	sum = io.MultiWriter(md, &totalCount, &dataCount, allSum)
+	if err := checkFiles(io.TeeReader(r, sum), digests); err != nil {
 		return err
 	}
 
@@ -57,7 +72,16 @@ func Check(file string) error {
 		err2 = sig.CompareMD5(md)
 		err3 = sig.CompareSha256(sh2)
 	)
-	return hasErrors(err1, err2, err3, err4)
+	if err := hasErrors(err1, err2, err3, err4); err != nil || keyring == nil {
+		return err
+	}
+	if err := hdrSig.Verify(keyring); err != nil {
+		return fmt.Errorf("header: %w", err)
+	}
+	if err := allSig.Verify(keyring); err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	return nil
 }
 
 func hasErrors(errs ...error) error {
@@ -88,6 +112,27 @@ type rpmSignature struct {
 	HeaderHash  string
 	DataMD5Hash string
 	DataSHAHash string
+
+	HeaderSig []byte
+	FullSig   []byte
+}
+
+func (r *rpmSignature) Verifiers(keyring *pgp.Keyring) (*pgp.Verifier, *pgp.Verifier, error) {
+	if keyring == nil {
+		return nil, nil, nil
+	}
+	if r.HeaderSig == nil || r.FullSig == nil {
+		return nil, nil, fmt.Errorf("signature: package is not signed")
+	}
+	hdr, err := pgp.NewVerifier(r.HeaderSig)
+	if err != nil {
+		return nil, nil, err
+	}
+	all, err := pgp.NewVerifier(r.FullSig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hdr, all, nil
 }
 
 func (r *rpmSignature) CompareLength(total, data int64) error {
@@ -127,6 +172,35 @@ func (r *rpmSignature) CompareSha256(sum hash.Hash) error {
 type rpmFileDigest struct {
 	File   string
 	Digest string
+	Algo   int64
+	Size   int64
+	Mode   int64
+	Flags  int64
+}
+
+func (d rpmFileDigest) IsDir() bool {
+	return d.Mode&rpmFileTypeMask == rpmFileTypeDir
+}
+
+func (d rpmFileDigest) IsRegular() bool {
+	return d.Mode&rpmFileTypeMask == rpmFileTypeReg
+}
+
+func (d rpmFileDigest) Hash() (hash.Hash, error) {
+	switch d.Algo {
+	case 0, rpmDigestMd5:
+		return md5.New(), nil
+	case rpmDigestSha1:
+		return sha1.New(), nil
+	case rpmDigestSha256:
+		return sha256.New(), nil
+	case rpmDigestSha384:
+		return sha512.New384(), nil
+	case rpmDigestSha512:
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("%d: unsupported digest algorithm", d.Algo)
+	}
 }
 
 func readSignatures(r io.Reader) (*rpmSignature, error) {
@@ -141,8 +215,12 @@ func readSignatures(r io.Reader) (*rpmSignature, error) {
 	if !bytes.Equal(buf, rpmHeader) {
 		return nil, fmt.Errorf("signature: not a valid rpm header (%x)", buf)
 	}
-	binary.Read(r, binary.BigEndian, &count)
-	binary.Read(r, binary.BigEndian, &size)
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
 
 	var (
 		index bytes.Buffer
@@ -193,6 +271,16 @@ func readSignatures(r io.Reader) (*rpmSignature, error) {
 				return nil, err
 			}
 			sig.DataMD5Hash = hex.EncodeToString(buf)
+		case rpmSigRSA, rpmSigDSA, rpmSigPGP, rpmSigGPG:
+			buf := make([]byte, int(size))
+			if _, err = io.ReadFull(rs, buf); err != nil {
+				return nil, err
+			}
+			if tag == rpmSigRSA || tag == rpmSigDSA {
+				sig.HeaderSig = buf
+			} else {
+				sig.FullSig = buf
+			}
 		case rpmSigLength:
 			binary.Read(store, binary.BigEndian, &size)
 			sig.TotalLen = int64(size)
@@ -205,7 +293,9 @@ func readSignatures(r io.Reader) (*rpmSignature, error) {
 	sigLength := tmp.Len() + index.Len() + rpmEntryLen
 	if mod := sigLength % 8; mod != 0 {
 		zs := make([]byte, 8-mod)
-		io.ReadFull(r, zs)
+		if _, err := io.ReadFull(r, zs); err != nil {
+			return nil, err
+		}
 	}
 	return &sig, nil
 }
@@ -222,20 +312,92 @@ func readSums(r io.Reader) ([]rpmFileDigest, error) {
 	if !bytes.Equal(buf, rpmHeader) {
 		return nil, fmt.Errorf("header: not a valid rpm header (%x)", buf)
 	}
-	binary.Read(r, binary.BigEndian, &count)
-	binary.Read(r, binary.BigEndian, &size)
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
 
 	var (
 		index bytes.Buffer
-		store bytes.Buffer
+		tmp   bytes.Buffer
 	)
 	if _, err := io.CopyN(&index, r, int64(rpmEntryLen*count)); err != nil {
 		return nil, err
 	}
-	if _, err := io.CopyN(&store, r, int64(size)); err != nil {
+	if _, err := io.CopyN(&tmp, r, int64(size)); err != nil {
 		return nil, err
 	}
-	return nil, nil
+	var (
+		files rpmFiles
+		algo  int64
+		store = bytes.NewReader(tmp.Bytes())
+	)
+	for i := 0; i < int(count); i++ {
+		var (
+			tag    int32
+			kind   int32
+			offset int32
+			total  int32
+		)
+		binary.Read(&index, binary.BigEndian, &tag)
+		binary.Read(&index, binary.BigEndian, &kind)
+		binary.Read(&index, binary.BigEndian, &offset)
+		binary.Read(&index, binary.BigEndian, &total)
+
+		if _, err := store.Seek(int64(offset), io.SeekStart); err != nil {
+			return nil, err
+		}
+		var err error
+		switch tag {
+		case rpmTagFileSizes, rpmTagLongFileSizes:
+			files.sizes, err = readIntArray(store, kind, total)
+		case rpmTagFileModes:
+			files.modes, err = readIntArray(store, kind, total)
+		case rpmTagFileFlags:
+			files.flags, err = readIntArray(store, kind, total)
+		case rpmTagDirIndexes:
+			files.indexes, err = readIntArray(store, kind, total)
+		case rpmTagFileDigests:
+			files.digests, err = readStringArray(store, total)
+		case rpmTagBasenames:
+			files.bases, err = readStringArray(store, total)
+		case rpmTagDirnames:
+			files.dirs, err = readStringArray(store, total)
+		case rpmTagFileDigestAlgo:
+			var list []int64
+			if list, err = readIntArray(store, kind, total); err == nil && len(list) > 0 {
+				algo = list[0]
+			}
+		default:
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if n := len(files.bases); len(files.indexes) != n || len(files.modes) != n || len(files.sizes) != n || len(files.digests) != n {
+		return nil, fmt.Errorf("header: file tags have inconsistent lengths")
+	}
+	var list []rpmFileDigest
+	for i := range files.bases {
+		ix := int(files.indexes[i])
+		if ix < 0 || ix >= len(files.dirs) {
+			return nil, fmt.Errorf("%s: invalid directory index %d", files.bases[i], ix)
+		}
+		d := rpmFileDigest{
+			File:   strings.TrimPrefix(path.Join(files.dirs[ix], files.bases[i]), "/"),
+			Digest: files.digests[i],
+			Algo:   algo,
+			Size:   files.sizes[i],
+			Mode:   files.modes[i],
+		}
+		if i < len(files.flags) {
+			d.Flags = files.flags[i]
+		}
+		list = append(list, d)
+	}
+	return list, nil
 }
 
 func checkFiles(r io.Reader, digests []rpmFileDigest) error {
@@ -243,7 +405,14 @@ func checkFiles(r io.Reader, digests []rpmFileDigest) error {
 	if err != nil {
 		return err
 	}
-	cp := cpio.NewReader(z)
+	var (
+		errs  []error
+		files = make(map[string]rpmFileDigest)
+		cp    = cpio.NewReader(z)
+	)
+	for _, d := range digests {
+		files[d.File] = d
+	}
 	for {
 		h, err := cp.Next()
 		if err != nil {
@@ -252,8 +421,49 @@ func checkFiles(r io.Reader, digests []rpmFileDigest) error {
 			}
 			return err
 		}
-		io.CopyN(io.Discard, cp, int64(h.Size))
+		name := strings.TrimPrefix(strings.TrimPrefix(h.Filename, "."), "/")
+		d, ok := files[name]
+		if !ok || !d.IsRegular() {
+			if _, err := io.CopyN(io.Discard, cp, int64(h.Size)); err != nil {
+				return err
+			}
+		}
+		if !ok {
+			isDir := h.Mode&int64(os.ModeDir) != 0 || h.Mode&rpmFileTypeMask == rpmFileTypeDir
+			if !isDir {
+				errs = append(errs, fmt.Errorf("%s: file in payload but not in header", name))
+			}
+			continue
+		}
+		delete(files, name)
+		if !d.IsRegular() {
+			continue
+		}
+		if perm := h.Mode & 0o7777; perm != d.Mode&0o7777 {
+			errs = append(errs, fmt.Errorf("%s: mode mismatched (%o != %o)", name, d.Mode&0o7777, perm))
+		}
+		if h.Size != d.Size {
+			errs = append(errs, fmt.Errorf("%s: size mismatched (%d != %d)", name, d.Size, h.Size))
+		}
+		sum, err := d.Hash()
+		if err != nil {
+			return err
+		}
+		if _, err := io.CopyN(sum, cp, int64(h.Size)); err != nil {
+			return err
+		}
+		if digest := hex.EncodeToString(sum.Sum(nil)); d.Digest != "" && digest != d.Digest {
+			errs = append(errs, fmt.Errorf("%s: checksum mismatched (%s)", name, d.Digest))
+		}
 	}
-	// io.Copy(io.Discard, r)
-	return nil
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+	for _, d := range digests {
+		if _, ok := files[d.File]; !ok || d.IsDir() || d.Flags&packfile.FileFlagGhost != 0 {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: file in header but not in payload", d.File))
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,313 @@
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+func Compare(kind, fst, snd string) (int, error) {
+	switch kind {
+	case packfile.Deb, packfile.Ipk:
+		return CompareDeb(fst, snd)
+	case packfile.Rpm, packfile.Pacman:
+		return CompareRpm(fst, snd)
+	default:
+		return 0, fmt.Errorf("%s: version comparison not supported", kind)
+	}
+}
+
+func Match(kind string, dep packfile.Dependency, version string) (bool, error) {
+	if dep.Version == "" {
+		return true, nil
+	}
+	cmp, err := Compare(kind, version, dep.Version)
+	if err != nil {
+		return false, err
+	}
+	switch dep.Constraint {
+	case packfile.ConstraintEq:
+		return cmp == 0, nil
+	case packfile.ConstraintGt:
+		return cmp > 0, nil
+	case packfile.ConstraintGe, "":
+		return cmp >= 0, nil
+	case packfile.ConstraintLt:
+		return cmp < 0, nil
+	case packfile.ConstraintLe:
+		return cmp <= 0, nil
+	default:
+		return false, fmt.Errorf("%s: unknown constraint", dep.Constraint)
+	}
+}
+
+type Version struct {
+	Epoch    int
+	Upstream string
+	Revision string
+}
+
+func Parse(str string) (Version, error) {
+	var (
+		v    Version
+		orig = str
+	)
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return v, fmt.Errorf("empty version")
+	}
+	if strings.ContainsFunc(str, unicode.IsSpace) {
+		return v, fmt.Errorf("%s: version contains spaces", orig)
+	}
+	if e, rest, ok := strings.Cut(str, ":"); ok {
+		n, err := strconv.Atoi(e)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("%s: invalid epoch in version", orig)
+		}
+		v.Epoch = n
+		str = rest
+	}
+	if ix := strings.LastIndex(str, "-"); ix >= 0 {
+		v.Revision = str[ix+1:]
+		str = str[:ix]
+		if v.Revision == "" {
+			return v, fmt.Errorf("%s: empty revision in version", orig)
+		}
+	}
+	if str == "" {
+		return v, fmt.Errorf("%s: empty upstream version", orig)
+	}
+	v.Upstream = str
+	return v, nil
+}
+
+func (v Version) String() string {
+	var str strings.Builder
+	if v.Epoch > 0 {
+		str.WriteString(strconv.Itoa(v.Epoch))
+		str.WriteByte(':')
+	}
+	str.WriteString(v.Upstream)
+	if v.Revision != "" {
+		str.WriteByte('-')
+		str.WriteString(v.Revision)
+	}
+	return str.String()
+}
+
+func CompareDeb(fst, snd string) (int, error) {
+	a, err := parseDeb(fst)
+	if err != nil {
+		return 0, err
+	}
+	b, err := parseDeb(snd)
+	if err != nil {
+		return 0, err
+	}
+	if a.Epoch != b.Epoch {
+		return compareInt(a.Epoch, b.Epoch), nil
+	}
+	if cmp := compareDebPart(a.Upstream, b.Upstream); cmp != 0 {
+		return cmp, nil
+	}
+	return compareDebPart(a.Revision, b.Revision), nil
+}
+
+func parseDeb(str string) (Version, error) {
+	v, err := Parse(str)
+	if err != nil {
+		return v, err
+	}
+	if !isDigit(v.Upstream[0]) {
+		return v, fmt.Errorf("%s: version does not start with a digit", str)
+	}
+	return v, nil
+}
+
+func compareDebPart(fst, snd string) int {
+	var i, j int
+	for i < len(fst) || j < len(snd) {
+		for (i < len(fst) && !isDigit(fst[i])) || (j < len(snd) && !isDigit(snd[j])) {
+			var ac, bc int
+			if i < len(fst) {
+				ac = debOrder(fst[i])
+			}
+			if j < len(snd) {
+				bc = debOrder(snd[j])
+			}
+			if ac != bc {
+				return compareInt(ac, bc)
+			}
+			i++
+			j++
+		}
+		for i < len(fst) && fst[i] == '0' {
+			i++
+		}
+		for j < len(snd) && snd[j] == '0' {
+			j++
+		}
+		var diff int
+		for i < len(fst) && j < len(snd) && isDigit(fst[i]) && isDigit(snd[j]) {
+			if diff == 0 {
+				diff = compareInt(int(fst[i]), int(snd[j]))
+			}
+			i++
+			j++
+		}
+		if i < len(fst) && isDigit(fst[i]) {
+			return 1
+		}
+		if j < len(snd) && isDigit(snd[j]) {
+			return -1
+		}
+		if diff != 0 {
+			return diff
+		}
+	}
+	return 0
+}
+
+func debOrder(c byte) int {
+	switch {
+	case isDigit(c):
+		return 0
+	case isAlpha(c):
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+func CompareRpm(fst, snd string) (int, error) {
+	a, err := Parse(fst)
+	if err != nil {
+		return 0, err
+	}
+	b, err := Parse(snd)
+	if err != nil {
+		return 0, err
+	}
+	if a.Epoch != b.Epoch {
+		return compareInt(a.Epoch, b.Epoch), nil
+	}
+	if cmp := compareRpmPart(a.Upstream, b.Upstream); cmp != 0 {
+		return cmp, nil
+	}
+	if a.Revision == "" || b.Revision == "" {
+		return 0, nil
+	}
+	return compareRpmPart(a.Revision, b.Revision), nil
+}
+
+func compareRpmPart(fst, snd string) int {
+	if fst == snd {
+		return 0
+	}
+	var i, j int
+	for i < len(fst) || j < len(snd) {
+		for i < len(fst) && !isAlnum(fst[i]) && fst[i] != '~' && fst[i] != '^' {
+			i++
+		}
+		for j < len(snd) && !isAlnum(snd[j]) && snd[j] != '~' && snd[j] != '^' {
+			j++
+		}
+		if (i < len(fst) && fst[i] == '~') || (j < len(snd) && snd[j] == '~') {
+			if i >= len(fst) || fst[i] != '~' {
+				return 1
+			}
+			if j >= len(snd) || snd[j] != '~' {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+		if (i < len(fst) && fst[i] == '^') || (j < len(snd) && snd[j] == '^') {
+			if i >= len(fst) {
+				return -1
+			}
+			if j >= len(snd) {
+				return 1
+			}
+			if fst[i] != '^' {
+				return 1
+			}
+			if snd[j] != '^' {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+		if i >= len(fst) || j >= len(snd) {
+			break
+		}
+		var (
+			x, y   = i, j
+			isnum  = isDigit(fst[i])
+			accept = isAlpha
+		)
+		if isnum {
+			accept = isDigit
+		}
+		for i < len(fst) && accept(fst[i]) {
+			i++
+		}
+		for j < len(snd) && accept(snd[j]) {
+			j++
+		}
+		if y == j {
+			if isnum {
+				return 1
+			}
+			return -1
+		}
+		a, b := fst[x:i], snd[y:j]
+		if isnum {
+			a = strings.TrimLeft(a, "0")
+			b = strings.TrimLeft(b, "0")
+			if len(a) != len(b) {
+				return compareInt(len(a), len(b))
+			}
+		}
+		if cmp := strings.Compare(a, b); cmp != 0 {
+			return cmp
+		}
+	}
+	if i >= len(fst) && j >= len(snd) {
+		return 0
+	}
+	if i < len(fst) {
+		return 1
+	}
+	return -1
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || isAlpha(c)
+}
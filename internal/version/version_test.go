@@ -0,0 +1,98 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/midbel/packit/internal/packfile"
+)
+
+func TestCompareDeb(t *testing.T) {
+	tests := []struct {
+		fst  string
+		snd  string
+		want int
+	}{
+		{fst: "1.0", snd: "1.0", want: 0},
+		{fst: "1.0~rc1", snd: "1.0", want: -1},
+		{fst: "1.0", snd: "1.0~rc1", want: 1},
+		{fst: "1.0~~", snd: "1.0~", want: -1},
+		{fst: "1.0~rc1", snd: "1.0~rc2", want: -1},
+		{fst: "1.2.3", snd: "1.2.10", want: -1},
+		{fst: "1.0a", snd: "1.0+", want: -1},
+		{fst: "1:1.0", snd: "2.0", want: 1},
+		{fst: "0:1.0", snd: "1.0", want: 0},
+		{fst: "1:1.0", snd: "2:0.1", want: -1},
+		{fst: "1.0-1", snd: "1.0-2", want: -1},
+		{fst: "1.0-10", snd: "1.0-9", want: 1},
+		{fst: "1.0-0", snd: "1.0", want: 0},
+		{fst: "1.0-1~bpo1", snd: "1.0-1", want: -1},
+		{fst: "2:1.0-1", snd: "2:1.0-1", want: 0},
+	}
+	for _, tt := range tests {
+		got, err := Compare(packfile.Deb, tt.fst, tt.snd)
+		if err != nil {
+			t.Errorf("%s/%s: unexpected error: %s", tt.fst, tt.snd, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s/%s: want %d, got %d", tt.fst, tt.snd, tt.want, got)
+		}
+	}
+}
+
+func TestCompareRpm(t *testing.T) {
+	tests := []struct {
+		fst  string
+		snd  string
+		want int
+	}{
+		{fst: "1.0", snd: "1.0", want: 0},
+		{fst: "1.0~rc1", snd: "1.0", want: -1},
+		{fst: "1.0~rc1", snd: "1.0~rc2", want: -1},
+		{fst: "1.0^git1", snd: "1.0", want: 1},
+		{fst: "1.0^git1", snd: "1.0.1", want: -1},
+		{fst: "1.0^git1", snd: "1.0~rc1", want: 1},
+		{fst: "1.10", snd: "1.9", want: 1},
+		{fst: "1.0a", snd: "1.0", want: 1},
+		{fst: "a", snd: "1", want: -1},
+		{fst: "1:1.0", snd: "2.0", want: 1},
+		{fst: "1:1.0", snd: "2:0.1", want: -1},
+		{fst: "1.0-1", snd: "1.0-2", want: -1},
+		{fst: "1.0-2", snd: "1.0", want: 0},
+		{fst: "1.0-1.el9", snd: "1.0-1.el10", want: -1},
+	}
+	for _, tt := range tests {
+		got, err := Compare(packfile.Rpm, tt.fst, tt.snd)
+		if err != nil {
+			t.Errorf("%s/%s: unexpected error: %s", tt.fst, tt.snd, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s/%s: want %d, got %d", tt.fst, tt.snd, tt.want, got)
+		}
+	}
+}
+
+func TestCompareInvalid(t *testing.T) {
+	tests := []struct {
+		kind string
+		fst  string
+		snd  string
+	}{
+		{kind: packfile.Deb, fst: "a:1", snd: "1"},
+		{kind: packfile.Deb, fst: "", snd: "1.0"},
+		{kind: packfile.Deb, fst: "1.0", snd: "-1:1.0"},
+		{kind: packfile.Deb, fst: "1.0-", snd: "1.0"},
+		{kind: packfile.Deb, fst: "abc", snd: "1.0"},
+		{kind: packfile.Deb, fst: "1.0 1", snd: "1.0"},
+		{kind: packfile.Rpm, fst: ":1.0", snd: "1.0"},
+		{kind: packfile.Rpm, fst: "1:", snd: "1.0"},
+		{kind: packfile.Rpm, fst: "1.0", snd: "-1"},
+		{kind: packfile.Apk, fst: "1.0", snd: "1.0"},
+	}
+	for _, tt := range tests {
+		if _, err := Compare(tt.kind, tt.fst, tt.snd); err == nil {
+			t.Errorf("%s: %q/%q: expected error", tt.kind, tt.fst, tt.snd)
+		}
+	}
+}